@@ -98,10 +98,12 @@ func (c Certificate) Expires() time.Time {
 // GetFingerprints returns the list of certificate fingerprints, one of which
 // is computed with the digest algorithm used in the certificate signature.
 func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
-	fingerprintAlgorithms := []crypto.Hash{crypto.SHA256}
-	res := make([]DTLSFingerprint, len(fingerprintAlgorithms))
+	return c.getFingerprints([]crypto.Hash{crypto.SHA256})
+}
+
+func (c Certificate) getFingerprints(fingerprintAlgorithms []crypto.Hash) ([]DTLSFingerprint, error) {
+	res := make([]DTLSFingerprint, 0, len(fingerprintAlgorithms))
 
-	i := 0
 	for _, algo := range fingerprintAlgorithms {
 		name, err := fingerprint.StringFromHash(algo)
 		if err != nil {
@@ -111,13 +113,13 @@ func (c Certificate) GetFingerprints() ([]DTLSFingerprint, error) {
 		if err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrFailedToGenerateCertificateFingerprint, err)
 		}
-		res[i] = DTLSFingerprint{
+		res = append(res, DTLSFingerprint{
 			Algorithm: name,
 			Value:     value,
-		}
+		})
 	}
 
-	return res[:i+1], nil
+	return res, nil
 }
 
 // GenerateCertificate causes the creation of an X.509 certificate and
@@ -0,0 +1,355 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCertificateRotateBefore = 7 * 24 * time.Hour
+	defaultCertificateOverlap      = time.Hour
+	certificateRotateRetryInterval = time.Minute
+)
+
+// CertificateStore persists the certificates of a CertificateRotator, so the
+// same certificate and thereby the same fingerprint survives restarts.
+type CertificateStore interface {
+	// Load returns the stored certificates, newest first. An empty store
+	// returns no certificates and no error.
+	Load() ([]Certificate, error)
+
+	// Save replaces the stored certificates
+	Save(certificates []Certificate) error
+}
+
+type memoryCertificateStore struct {
+	mu           sync.Mutex
+	certificates []Certificate
+}
+
+// NewMemoryCertificateStore returns a CertificateStore that keeps the
+// certificates in memory, e.g. to share them between the APIs of one process.
+func NewMemoryCertificateStore() CertificateStore {
+	return &memoryCertificateStore{}
+}
+
+func (s *memoryCertificateStore) Load() ([]Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Certificate{}, s.certificates...), nil
+}
+
+func (s *memoryCertificateStore) Save(certificates []Certificate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.certificates = append([]Certificate{}, certificates...)
+	return nil
+}
+
+type fileCertificateStore struct {
+	path string
+}
+
+// NewFileCertificateStore returns a CertificateStore that keeps the
+// certificates PEM encoded in the file at path. The file contains the private
+// keys and is therefore written with 0600 permissions.
+func NewFileCertificateStore(path string) CertificateStore {
+	return &fileCertificateStore{path: path}
+}
+
+func (s *fileCertificateStore) Load() ([]Certificate, error) {
+	pems, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	certificates := []Certificate{}
+	for rest := bytes.TrimSpace(pems); len(rest) != 0; rest = bytes.TrimSpace(rest) {
+		// Every certificate is a CERTIFICATE block followed by its PRIVATE KEY block
+		next := rest
+		for i := 0; i < 2; i++ {
+			var block *pem.Block
+			if block, next = pem.Decode(next); block == nil {
+				return nil, errCertificatePEMFormatError
+			}
+		}
+
+		certificate, err := CertificateFromPEM(string(rest[:len(rest)-len(next)]))
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, *certificate)
+		rest = next
+	}
+
+	return certificates, nil
+}
+
+func (s *fileCertificateStore) Save(certificates []Certificate) error {
+	var pems strings.Builder
+	for _, certificate := range certificates {
+		p, err := certificate.PEM()
+		if err != nil {
+			return err
+		}
+		pems.WriteString(p)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated store
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(pems.String()), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// CertificateRotatorConfig configures NewCertificateRotator
+type CertificateRotatorConfig struct {
+	// Store persists the certificates. Defaults to NewMemoryCertificateStore.
+	Store CertificateStore
+
+	// Generate creates a new certificate. Defaults to GenerateCertificate
+	// with an ECDSA P-256 key.
+	Generate func() (*Certificate, error)
+
+	// RotateBefore is how long before its expiry the current certificate is
+	// replaced. Generated certificates have to be valid for longer.
+	// Defaults to 7 days.
+	RotateBefore time.Duration
+
+	// Overlap is how long a replaced certificate is still handed out after
+	// the current one. DTLS only presents the current certificate, but the
+	// fingerprints of both are signaled, so negotiations that are validated
+	// against the previous fingerprint keep working while the new one is
+	// pinned. Defaults to 1 hour, a negative value disables the overlap.
+	Overlap time.Duration
+}
+
+// CertificateRotator provides the certificates of new PeerConnections. It
+// reuses the stored certificate and replaces it before it expires. A timer
+// rotates the certificate when it is due, even if no PeerConnection asks for
+// certificates. Use it with SettingEngine.SetCertificateRotator.
+type CertificateRotator struct {
+	mu           sync.Mutex
+	store        CertificateStore
+	generate     func() (*Certificate, error)
+	rotateBefore time.Duration
+	overlap      time.Duration
+
+	// certificates holds the current certificate first, followed by the
+	// replaced ones that are handed out until previousUntil
+	certificates  []Certificate
+	previousUntil []time.Time
+
+	timer  *time.Timer
+	closed bool
+
+	onRotateHandler func(current Certificate, previous []Certificate)
+}
+
+// NewCertificateRotator loads the certificates of config.Store and generates
+// a new one if none of them is usable. The replaced certificates found in the
+// store are handed out for another Overlap, as the time they were replaced
+// isn't stored.
+func NewCertificateRotator(config CertificateRotatorConfig) (*CertificateRotator, error) {
+	r := &CertificateRotator{
+		store:        config.Store,
+		generate:     config.Generate,
+		rotateBefore: config.RotateBefore,
+		overlap:      config.Overlap,
+	}
+	if r.store == nil {
+		r.store = NewMemoryCertificateStore()
+	}
+	if r.generate == nil {
+		r.generate = func() (*Certificate, error) {
+			sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+			return GenerateCertificate(sk)
+		}
+	}
+	if r.rotateBefore == 0 {
+		r.rotateBefore = defaultCertificateRotateBefore
+	}
+	if r.overlap == 0 {
+		r.overlap = defaultCertificateOverlap
+	}
+
+	certificates, err := r.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	r.certificates = certificates
+	for i := 1; i < len(certificates); i++ {
+		r.previousUntil = append(r.previousUntil, time.Now().Add(r.overlap))
+	}
+
+	if _, err := r.Certificates(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// OnRotate sets an event handler which is invoked after a new certificate
+// replaced the current one, e.g. to pin its fingerprint. previous holds the
+// replaced certificate, which is handed out next to current until the
+// Overlap ends. The handler may run on the goroutine of the rotation timer.
+func (r *CertificateRotator) OnRotate(f func(current Certificate, previous []Certificate)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRotateHandler = f
+}
+
+// Certificates returns the certificates for a new PeerConnection, the current
+// one first, followed by the replaced ones within their Overlap. The current
+// certificate is rotated if it expires within RotateBefore.
+func (r *CertificateRotator) Certificates() ([]Certificate, error) {
+	return r.update(false)
+}
+
+// Rotate replaces the current certificate immediately
+func (r *CertificateRotator) Rotate() error {
+	_, err := r.update(true)
+	return err
+}
+
+// Close stops the rotation timer. Certificates keeps rotating on demand.
+func (r *CertificateRotator) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+func (r *CertificateRotator) update(force bool) ([]Certificate, error) {
+	r.mu.Lock()
+	replaced, err := r.rotate(force, time.Now())
+	r.schedule(err)
+	certificates := append([]Certificate{}, r.certificates...)
+	handler := r.onRotateHandler
+	r.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if replaced != nil && handler != nil {
+		handler(certificates[0], replaced)
+	}
+	return certificates, nil
+}
+
+// rotate drops the replaced certificates that expired or whose overlap ended
+// and generates a new current certificate when needed. It returns the
+// replaced certificates if a new certificate was generated.
+func (r *CertificateRotator) rotate(force bool, now time.Time) ([]Certificate, error) {
+	usable := func(certificate Certificate) bool {
+		expires := certificate.Expires()
+		return expires.IsZero() || now.Before(expires)
+	}
+
+	previous, previousUntil := []Certificate{}, []time.Time{}
+	if len(r.certificates) > 1 {
+		for i, certificate := range r.certificates[1:] {
+			if usable(certificate) && now.Before(r.previousUntil[i]) {
+				previous = append(previous, certificate)
+				previousUntil = append(previousUntil, r.previousUntil[i])
+			}
+		}
+	}
+
+	if !force && len(r.certificates) != 0 {
+		if expires := r.certificates[0].Expires(); expires.IsZero() || now.Add(r.rotateBefore).Before(expires) {
+			if len(previous) == len(r.certificates)-1 {
+				return nil, nil
+			}
+
+			r.certificates = append([]Certificate{r.certificates[0]}, previous...)
+			r.previousUntil = previousUntil
+			return nil, r.store.Save(r.certificates)
+		}
+	}
+
+	certificate, err := r.generate()
+	if err != nil {
+		return nil, err
+	}
+	// It would be rotated again right away, over and over
+	if expires := certificate.Expires(); !expires.IsZero() && !now.Add(r.rotateBefore).Before(expires) {
+		return nil, errCertificateRotatorExpiresTooSoon
+	}
+
+	replaced := []Certificate{}
+	if len(r.certificates) != 0 && usable(r.certificates[0]) {
+		replaced = append(replaced, r.certificates[0])
+		if r.overlap > 0 {
+			previous = append([]Certificate{r.certificates[0]}, previous...)
+			previousUntil = append([]time.Time{now.Add(r.overlap)}, previousUntil...)
+		}
+	}
+	if r.overlap < 0 {
+		previous, previousUntil = nil, nil
+	}
+
+	certificates := append([]Certificate{*certificate}, previous...)
+	if err := r.store.Save(certificates); err != nil {
+		return nil, err
+	}
+	r.certificates, r.previousUntil = certificates, previousUntil
+	return replaced, nil
+}
+
+// schedule arms the timer for the next rotation or the end of the next
+// overlap, or for a retry if the last rotation failed. The caller must hold
+// the lock.
+func (r *CertificateRotator) schedule(rotateErr error) {
+	if r.closed {
+		return
+	}
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+
+	now := time.Now()
+	next := time.Time{}
+	switch {
+	case rotateErr != nil:
+		next = now.Add(certificateRotateRetryInterval)
+	case len(r.certificates) != 0:
+		if expires := r.certificates[0].Expires(); !expires.IsZero() {
+			next = expires.Add(-r.rotateBefore)
+		}
+		for _, until := range r.previousUntil {
+			if next.IsZero() || until.Before(next) {
+				next = until
+			}
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	r.timer = time.AfterFunc(next.Sub(now), func() {
+		_, _ = r.update(false)
+	})
+}
@@ -0,0 +1,204 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generateCertificateValidFor(t *testing.T, d time.Duration) *Certificate {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	certificate, err := NewCertificate(sk, x509.Certificate{
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(d),
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+	})
+	assert.NoError(t, err)
+	return certificate
+}
+
+func newTempCertificateStorePath(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "certificates.pem")
+}
+
+func TestFileCertificateStore(t *testing.T) {
+	path := newTempCertificateStorePath(t)
+
+	store := NewFileCertificateStore(path)
+
+	certificates, err := store.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, certificates)
+
+	saved := []Certificate{*generateCertificateValidFor(t, time.Hour), *generateCertificateValidFor(t, time.Hour)}
+	assert.NoError(t, store.Save(saved))
+
+	certificates, err = store.Load()
+	assert.NoError(t, err)
+	assert.Len(t, certificates, 2)
+	for i := range saved {
+		assert.True(t, saved[i].Equals(certificates[i]))
+	}
+}
+
+func TestCertificateRotator(t *testing.T) {
+	t.Run("Reuse stored certificate", func(t *testing.T) {
+		path := newTempCertificateStorePath(t)
+
+		store := NewFileCertificateStore(path)
+
+		first, err := NewCertificateRotator(CertificateRotatorConfig{Store: store})
+		assert.NoError(t, err)
+		firstCertificates, err := first.Certificates()
+		assert.NoError(t, err)
+		assert.Len(t, firstCertificates, 1)
+
+		// A restarted process picks up the same certificate
+		second, err := NewCertificateRotator(CertificateRotatorConfig{Store: store})
+		assert.NoError(t, err)
+		secondCertificates, err := second.Certificates()
+		assert.NoError(t, err)
+		assert.Len(t, secondCertificates, 1)
+		assert.True(t, firstCertificates[0].Equals(secondCertificates[0]))
+	})
+
+	t.Run("Rotate before expiry", func(t *testing.T) {
+		store := NewMemoryCertificateStore()
+		expiring := generateCertificateValidFor(t, time.Hour)
+		expired := generateCertificateValidFor(t, -time.Minute)
+		assert.NoError(t, store.Save([]Certificate{*expiring, *expired}))
+
+		r, err := NewCertificateRotator(CertificateRotatorConfig{Store: store, RotateBefore: 2 * time.Hour})
+		assert.NoError(t, err)
+		defer r.Close()
+
+		// The replacement is handed out first, the replaced certificate
+		// overlaps and the expired one is dropped
+		certificates, err := r.Certificates()
+		assert.NoError(t, err)
+		assert.Len(t, certificates, 2)
+		assert.False(t, certificates[0].Equals(*expiring))
+		assert.True(t, certificates[1].Equals(*expiring))
+
+		stored, err := store.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, certificates, stored)
+	})
+
+	t.Run("Overlap ends", func(t *testing.T) {
+		store := NewMemoryCertificateStore()
+		r, err := NewCertificateRotator(CertificateRotatorConfig{Store: store, Overlap: 100 * time.Millisecond})
+		assert.NoError(t, err)
+		defer r.Close()
+
+		assert.NoError(t, r.Rotate())
+		certificates, err := r.Certificates()
+		assert.NoError(t, err)
+		assert.Len(t, certificates, 2)
+
+		// The timer drops the replaced certificate without a call to Certificates
+		assert.Eventually(t, func() bool {
+			stored, err := store.Load()
+			return err == nil && len(stored) == 1 && stored[0].Equals(certificates[0])
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("No overlap", func(t *testing.T) {
+		r, err := NewCertificateRotator(CertificateRotatorConfig{Overlap: -1})
+		assert.NoError(t, err)
+		defer r.Close()
+
+		assert.NoError(t, r.Rotate())
+		certificates, err := r.Certificates()
+		assert.NoError(t, err)
+		assert.Len(t, certificates, 1)
+	})
+
+	t.Run("Timer rotates", func(t *testing.T) {
+		// Every certificate is due for rotation shortly after it was generated.
+		// NotAfter is truncated to seconds, so give it two.
+		r, err := NewCertificateRotator(CertificateRotatorConfig{
+			Generate: func() (*Certificate, error) {
+				return generateCertificateValidFor(t, time.Hour), nil
+			},
+			RotateBefore: time.Hour - 2*time.Second,
+		})
+		assert.NoError(t, err)
+		defer r.Close()
+
+		rotated := make(chan struct{}, 1)
+		r.OnRotate(func(Certificate, []Certificate) {
+			select {
+			case rotated <- struct{}{}:
+			default:
+			}
+		})
+
+		select {
+		case <-rotated:
+		case <-time.After(5 * time.Second):
+			t.Fatal("certificate was not rotated by the timer")
+		}
+	})
+
+	t.Run("Certificate expires too soon", func(t *testing.T) {
+		_, err := NewCertificateRotator(CertificateRotatorConfig{
+			Generate: func() (*Certificate, error) {
+				return generateCertificateValidFor(t, time.Hour), nil
+			},
+			RotateBefore: 2 * time.Hour,
+		})
+		assert.ErrorIs(t, err, errCertificateRotatorExpiresTooSoon)
+	})
+
+	t.Run("OnRotate", func(t *testing.T) {
+		r, err := NewCertificateRotator(CertificateRotatorConfig{})
+		assert.NoError(t, err)
+		before, err := r.Certificates()
+		assert.NoError(t, err)
+
+		var rotatedCurrent Certificate
+		var rotatedPrevious []Certificate
+		r.OnRotate(func(current Certificate, previous []Certificate) {
+			rotatedCurrent, rotatedPrevious = current, previous
+		})
+		assert.NoError(t, r.Rotate())
+
+		after, err := r.Certificates()
+		assert.NoError(t, err)
+		assert.True(t, rotatedCurrent.Equals(after[0]))
+		assert.Len(t, rotatedPrevious, 1)
+		assert.True(t, rotatedPrevious[0].Equals(before[0]))
+		assert.True(t, after[1].Equals(before[0]))
+	})
+}
+
+func TestPeerConnection_CertificateRotator(t *testing.T) {
+	r, err := NewCertificateRotator(CertificateRotatorConfig{})
+	assert.NoError(t, err)
+	certificates, err := r.Certificates()
+	assert.NoError(t, err)
+
+	s := SettingEngine{}
+	s.SetCertificateRotator(r)
+	api := NewAPI(WithSettingEngine(s))
+
+	for i := 0; i < 2; i++ {
+		pc, err := api.NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		assert.True(t, certificates[0].Equals(pc.GetConfiguration().Certificates[0]))
+		assert.NoError(t, pc.Close())
+	}
+}
@@ -578,3 +578,58 @@ func TestDataChannel_NonStandardSessionDescription(t *testing.T) {
 	<-onDataChannelCalled
 	closePairNow(t, offerPC, answerPC)
 }
+
+func TestDataChannel_CreateFromLayout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	maxRetransmits := uint16(0)
+	layout := DataChannelLayout{Entries: []DataChannelLayoutEntry{
+		{Label: "control", Role: DTLSRoleClient},
+		{Label: "bulk", Role: DTLSRoleServer, Count: 2, MaxRetransmits: &maxRetransmits},
+	}}
+
+	offerChannels, err := offerPC.CreateDataChannelsFromLayout(layout)
+	assert.NoError(t, err)
+	answerChannels, err := answerPC.CreateDataChannelsFromLayout(layout)
+	assert.NoError(t, err)
+
+	assert.Equal(t, len(offerChannels), 3)
+	assert.Equal(t, len(answerChannels), 3)
+	for i := range offerChannels {
+		assert.Equal(t, *offerChannels[i].ID(), *answerChannels[i].ID())
+		assert.True(t, offerChannels[i].Negotiated())
+	}
+
+	// The same layout can't be created twice on one PeerConnection
+	_, err = offerPC.CreateDataChannelsFromLayout(DataChannelLayout{Entries: []DataChannelLayoutEntry{
+		{Label: "control", ID: offerChannels[0].ID()},
+	}})
+	assert.ErrorIs(t, err, ErrDataChannelLayoutDuplicateID)
+
+	answerPC.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != "initial_data_channel" {
+			t.Fatal("Negotiated DataChannels must not be announced")
+		}
+	})
+
+	received := make(chan struct{})
+	answerChannels[2].OnMessage(func(msg DataChannelMessage) {
+		assert.Equal(t, "bulk", string(msg.Data))
+		close(received)
+	})
+	offerChannels[2].OnOpen(func() {
+		assert.NoError(t, offerChannels[2].SendText("bulk"))
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-received
+
+	closePairNow(t, offerPC, answerPC)
+}
@@ -0,0 +1,177 @@
+package webrtc
+
+import (
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
+)
+
+// DataChannelLayout is a declarative description of a set of pre-negotiated
+// DataChannels. When both peers create their DataChannels from the same
+// layout every channel ends up with the same ID on each side, so no in-band
+// announcement is needed and the channels are usable as soon as SCTP is up.
+type DataChannelLayout struct {
+	Entries []DataChannelLayoutEntry
+}
+
+// DataChannelLayoutEntry describes one or more DataChannels that share the
+// same label and reliability settings.
+type DataChannelLayoutEntry struct {
+	// Label of every DataChannel created for this entry.
+	Label string
+
+	// Count is the number of DataChannels created for this entry.
+	// A Count of 0 is treated as 1.
+	Count uint16
+
+	// Role restricts the IDs of this entry to the ones the given DTLS role would
+	// allocate for its own in-band channels: even for DTLSRoleClient, odd for
+	// DTLSRoleServer. DTLSRoleAuto allows any ID.
+	Role DTLSRole
+
+	// ID pins the ID of the first DataChannel of this entry. The following
+	// channels use the next IDs with the same parity if Role is set, or
+	// consecutive IDs otherwise. If ID is nil the lowest free IDs are used.
+	ID *uint16
+
+	// Ordered indicates if data is allowed to be delivered out of order.
+	// Defaults to true.
+	Ordered *bool
+
+	// MaxPacketLifeTime limits the time (in milliseconds) during which the
+	// channels will transmit or retransmit data if not acknowledged.
+	MaxPacketLifeTime *uint16
+
+	// MaxRetransmits limits the number of times the channels will retransmit
+	// data if not successfully delivered.
+	MaxRetransmits *uint16
+
+	// Protocol describes the subprotocol name used for the channels.
+	Protocol string
+}
+
+// Compile resolves the IDs of every DataChannel in the layout and returns
+// their parameters in layout order. IDs must be lower than maxChannels, which
+// is usually SCTPTransport.MaxChannels. The IDs only depend on the layout, so
+// both peers compile the same layout to the same IDs.
+func (l DataChannelLayout) Compile(maxChannels uint16) ([]DataChannelParameters, error) {
+	used := map[uint16]struct{}{}
+
+	// IDs pinned by any entry must not be handed out by the automatic
+	// allocation of an earlier entry
+	pinned := map[uint16]struct{}{}
+	for _, e := range l.Entries {
+		if e.ID == nil {
+			continue
+		}
+		step := e.idStep()
+		for i, id := uint16(0), uint32(*e.ID); i < e.count(); i, id = i+1, id+uint32(step) {
+			if id < uint32(maxChannels) {
+				pinned[uint16(id)] = struct{}{}
+			}
+		}
+	}
+
+	var params []DataChannelParameters
+	for _, e := range l.Entries {
+		if len(e.Label) > 65535 {
+			return nil, &rtcerr.TypeError{Err: ErrStringSizeLimit}
+		}
+		if len(e.Protocol) > 65535 {
+			return nil, &rtcerr.TypeError{Err: ErrProtocolTooLarge}
+		}
+		if e.MaxPacketLifeTime != nil && e.MaxRetransmits != nil {
+			return nil, &rtcerr.TypeError{Err: ErrRetransmitsOrPacketLifeTime}
+		}
+
+		ids, err := e.allocateIDs(maxChannels, used, pinned)
+		if err != nil {
+			return nil, err
+		}
+
+		ordered := true
+		if e.Ordered != nil {
+			ordered = *e.Ordered
+		}
+
+		for i := range ids {
+			params = append(params, DataChannelParameters{
+				Label:             e.Label,
+				Protocol:          e.Protocol,
+				ID:                &ids[i],
+				Ordered:           ordered,
+				MaxPacketLifeTime: e.MaxPacketLifeTime,
+				MaxRetransmits:    e.MaxRetransmits,
+				Negotiated:        true,
+			})
+		}
+	}
+
+	return params, nil
+}
+
+func (e DataChannelLayoutEntry) count() uint16 {
+	if e.Count == 0 {
+		return 1
+	}
+	return e.Count
+}
+
+func (e DataChannelLayoutEntry) idStep() uint16 {
+	if e.Role == DTLSRoleClient || e.Role == DTLSRoleServer {
+		return 2
+	}
+	return 1
+}
+
+func (e DataChannelLayoutEntry) matchesRole(id uint16) bool {
+	switch e.Role {
+	case DTLSRoleClient:
+		return id%2 == 0
+	case DTLSRoleServer:
+		return id%2 == 1
+	default:
+		return true
+	}
+}
+
+func (e DataChannelLayoutEntry) allocateIDs(maxChannels uint16, used, pinned map[uint16]struct{}) ([]uint16, error) {
+	ids := make([]uint16, 0, e.count())
+	step := uint32(e.idStep())
+
+	if e.ID != nil {
+		if !e.matchesRole(*e.ID) {
+			return nil, &rtcerr.TypeError{Err: ErrDataChannelLayoutRoleMismatch}
+		}
+
+		for id := uint32(*e.ID); len(ids) < int(e.count()); id += step {
+			if id >= uint32(maxChannels) {
+				return nil, &rtcerr.OperationError{Err: ErrMaxDataChannelID}
+			}
+			if _, ok := used[uint16(id)]; ok {
+				return nil, &rtcerr.TypeError{Err: ErrDataChannelLayoutDuplicateID}
+			}
+			used[uint16(id)] = struct{}{}
+			ids = append(ids, uint16(id))
+		}
+		return ids, nil
+	}
+
+	id := uint32(0)
+	if e.Role == DTLSRoleServer {
+		id = 1
+	}
+	for ; len(ids) < int(e.count()); id += step {
+		if id >= uint32(maxChannels) {
+			return nil, &rtcerr.OperationError{Err: ErrMaxDataChannelID}
+		}
+		if _, ok := used[uint16(id)]; ok {
+			continue
+		}
+		if _, ok := pinned[uint16(id)]; ok {
+			continue
+		}
+		used[uint16(id)] = struct{}{}
+		ids = append(ids, uint16(id))
+	}
+
+	return ids, nil
+}
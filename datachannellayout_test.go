@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataChannelLayout_Compile(t *testing.T) {
+	u16 := func(v uint16) *uint16 { return &v }
+
+	idsOf := func(params []DataChannelParameters) []uint16 {
+		ids := []uint16{}
+		for _, p := range params {
+			ids = append(ids, *p.ID)
+		}
+		return ids
+	}
+
+	testCases := []struct {
+		name        string
+		layout      DataChannelLayout
+		maxChannels uint16
+		expectedIDs []uint16
+		expectedErr error
+	}{
+		{
+			name:        "automatic allocation",
+			layout:      DataChannelLayout{Entries: []DataChannelLayoutEntry{{Label: "a"}, {Label: "b", Count: 2}}},
+			maxChannels: 65535,
+			expectedIDs: []uint16{0, 1, 2},
+		},
+		{
+			name: "role parity",
+			layout: DataChannelLayout{Entries: []DataChannelLayoutEntry{
+				{Label: "client", Role: DTLSRoleClient, Count: 2},
+				{Label: "server", Role: DTLSRoleServer, Count: 2},
+			}},
+			maxChannels: 65535,
+			expectedIDs: []uint16{0, 2, 1, 3},
+		},
+		{
+			name: "pinned ranges are skipped by automatic allocation",
+			layout: DataChannelLayout{Entries: []DataChannelLayoutEntry{
+				{Label: "auto", Count: 2},
+				{Label: "pinned", ID: u16(0), Count: 2},
+			}},
+			maxChannels: 65535,
+			expectedIDs: []uint16{2, 3, 0, 1},
+		},
+		{
+			name: "pinned range with role",
+			layout: DataChannelLayout{Entries: []DataChannelLayoutEntry{
+				{Label: "pinned", ID: u16(11), Role: DTLSRoleServer, Count: 3},
+			}},
+			maxChannels: 65535,
+			expectedIDs: []uint16{11, 13, 15},
+		},
+		{
+			name: "overlapping pinned ranges",
+			layout: DataChannelLayout{Entries: []DataChannelLayoutEntry{
+				{Label: "a", ID: u16(0), Count: 3},
+				{Label: "b", ID: u16(2)},
+			}},
+			maxChannels: 65535,
+			expectedErr: ErrDataChannelLayoutDuplicateID,
+		},
+		{
+			name:        "pinned ID doesn't match role",
+			layout:      DataChannelLayout{Entries: []DataChannelLayoutEntry{{Label: "a", ID: u16(1), Role: DTLSRoleClient}}},
+			maxChannels: 65535,
+			expectedErr: ErrDataChannelLayoutRoleMismatch,
+		},
+		{
+			name:        "layout exceeds MaxChannels",
+			layout:      DataChannelLayout{Entries: []DataChannelLayoutEntry{{Label: "a", Role: DTLSRoleServer, Count: 3}}},
+			maxChannels: 4,
+			expectedErr: ErrMaxDataChannelID,
+		},
+		{
+			name: "both MaxPacketLifeTime and MaxRetransmits",
+			layout: DataChannelLayout{Entries: []DataChannelLayoutEntry{
+				{Label: "a", MaxPacketLifeTime: u16(1), MaxRetransmits: u16(1)},
+			}},
+			maxChannels: 65535,
+			expectedErr: ErrRetransmitsOrPacketLifeTime,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			params, err := testCase.layout.Compile(testCase.maxChannels)
+			if testCase.expectedErr != nil {
+				assert.True(t, errors.Is(err, testCase.expectedErr), "unexpected error %v", err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedIDs, idsOf(params))
+			for _, p := range params {
+				assert.True(t, p.Negotiated)
+				assert.True(t, p.Ordered)
+			}
+		})
+	}
+}
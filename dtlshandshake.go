@@ -0,0 +1,245 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/alert"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+)
+
+// DTLSHandshakeDiagnostics describes the progress of a DTLS handshake as seen
+// on the wire. It is meant to debug handshakes that fail or stall, e.g. because
+// a middlebox drops large flights.
+type DTLSHandshakeDiagnostics struct {
+	// LastSentMessage is the type of the last handshake message sent, e.g. "ClientHello"
+	LastSentMessage string
+
+	// LastReceivedMessage is the type of the last handshake message received
+	LastReceivedMessage string
+
+	// SentAlert is the last alert sent, e.g. "Alert Fatal: BadCertificate".
+	// Alerts sent after the ChangeCipherSpec are encrypted and not reported.
+	SentAlert string
+
+	// ReceivedAlert is the last alert received. Alerts received after the
+	// ChangeCipherSpec are encrypted and not reported.
+	ReceivedAlert string
+
+	// Retransmissions is the number of flights that had to be sent again
+	// because the remote didn't answer in time
+	Retransmissions int
+}
+
+// DTLSHandshakeError is returned by DTLSTransport.Start when the DTLS
+// handshake fails. It carries what was seen of the handshake until then.
+type DTLSHandshakeError struct {
+	Err         error
+	Diagnostics DTLSHandshakeDiagnostics
+}
+
+func (e *DTLSHandshakeError) Error() string {
+	d := e.Diagnostics
+	msg := fmt.Sprintf("DTLS handshake failed: %v (last sent %q, last received %q, %d retransmissions",
+		e.Err, d.LastSentMessage, d.LastReceivedMessage, d.Retransmissions)
+	if d.SentAlert != "" {
+		msg += fmt.Sprintf(", sent %q", d.SentAlert)
+	}
+	if d.ReceivedAlert != "" {
+		msg += fmt.Sprintf(", received %q", d.ReceivedAlert)
+	}
+	return msg + ")"
+}
+
+func (e *DTLSHandshakeError) Unwrap() error {
+	return e.Err
+}
+
+type dtlsFragmentKey struct {
+	messageSequence uint16
+	fragmentOffset  uint32
+}
+
+// dtlsHandshakeRecorder wraps the connection the DTLS handshake runs over and
+// inspects its plaintext records. pion/dtls doesn't export the negotiated
+// cipher suite and version or anything about the handshake progress, but
+// the ServerHello carrying them is sent in the clear.
+type dtlsHandshakeRecorder struct {
+	net.Conn
+
+	stopped atomic.Value // bool
+
+	mu          sync.Mutex
+	diagnostics DTLSHandshakeDiagnostics
+	version     protocol.Version
+	cipherSuite dtls.CipherSuiteID
+
+	// sent holds every handshake fragment sent so far. A datagram repeating
+	// the first fragment of the current flight is a retransmission.
+	sent          map[dtlsFragmentKey]bool
+	flightStart   dtlsFragmentKey
+	inFlight      bool
+	sentEncrypted bool
+}
+
+func newDTLSHandshakeRecorder(conn net.Conn) *dtlsHandshakeRecorder {
+	r := &dtlsHandshakeRecorder{
+		Conn: conn,
+		sent: map[dtlsFragmentKey]bool{},
+	}
+	r.stopped.Store(false)
+	return r
+}
+
+func (r *dtlsHandshakeRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if n > 0 && !r.stopped.Load().(bool) {
+		r.inspect(b[:n], false)
+	}
+	return n, err
+}
+
+func (r *dtlsHandshakeRecorder) Write(b []byte) (int, error) {
+	if !r.stopped.Load().(bool) {
+		r.inspect(b, true)
+	}
+	return r.Conn.Write(b)
+}
+
+// stop ends the inspection once the handshake has completed
+func (r *dtlsHandshakeRecorder) stop() {
+	r.stopped.Store(true)
+}
+
+func (r *dtlsHandshakeRecorder) getDiagnostics() DTLSHandshakeDiagnostics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.diagnostics
+}
+
+// negotiated returns the version and cipher suite picked by the ServerHello
+func (r *dtlsHandshakeRecorder) negotiated() (protocol.Version, dtls.CipherSuiteID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.version, r.cipherSuite
+}
+
+func (r *dtlsHandshakeRecorder) inspect(datagram []byte, outbound bool) {
+	records, err := recordlayer.UnpackDatagram(datagram)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newFragment, retransmitted := false, false
+	for _, record := range records {
+		h := recordlayer.Header{}
+		if err := h.Unmarshal(record); err != nil {
+			return
+		}
+		content := record[recordlayer.HeaderSize:]
+
+		switch {
+		case h.ContentType == protocol.ContentTypeAlert && h.Epoch == 0:
+			a := alert.Alert{}
+			if err := a.Unmarshal(content); err != nil {
+				continue
+			}
+			if outbound {
+				r.diagnostics.SentAlert = a.String()
+			} else {
+				r.diagnostics.ReceivedAlert = a.String()
+			}
+		case h.ContentType == protocol.ContentTypeHandshake && h.Epoch != 0:
+			// The Finished message is the only encrypted handshake message of DTLS 1.2
+			if outbound {
+				r.diagnostics.LastSentMessage = dtlsHandshakeTypeName(handshake.TypeFinished)
+				if r.sentEncrypted && !r.inFlight {
+					retransmitted = true
+				}
+				r.sentEncrypted = true
+			} else {
+				r.diagnostics.LastReceivedMessage = dtlsHandshakeTypeName(handshake.TypeFinished)
+				r.inFlight = false
+			}
+		case h.ContentType == protocol.ContentTypeHandshake:
+			hh := handshake.Header{}
+			if err := hh.Unmarshal(content); err != nil {
+				continue
+			}
+
+			if !outbound {
+				r.diagnostics.LastReceivedMessage = dtlsHandshakeTypeName(hh.Type)
+				r.inFlight = false
+			} else {
+				r.diagnostics.LastSentMessage = dtlsHandshakeTypeName(hh.Type)
+
+				key := dtlsFragmentKey{hh.MessageSequence, hh.FragmentOffset}
+				switch {
+				case !r.sent[key]:
+					if !r.inFlight && !newFragment {
+						r.flightStart = key
+					}
+					r.sent[key] = true
+					newFragment = true
+				case key == r.flightStart:
+					retransmitted = true
+				}
+			}
+
+			if hh.Type == handshake.TypeServerHello && hh.FragmentOffset == 0 && hh.FragmentLength == hh.Length {
+				body := content[handshake.HeaderLength:]
+
+				// MessageServerHello.Unmarshal reads the cipher suite without a
+				// bounds check, make sure everything up to the compression method is there
+				sessionIDStart := 2 + handshake.RandomLength + 1
+				if len(body) < sessionIDStart || len(body) < sessionIDStart+int(body[sessionIDStart-1])+3 {
+					continue
+				}
+
+				serverHello := handshake.MessageServerHello{}
+				if err := serverHello.Unmarshal(body); err == nil {
+					r.version = serverHello.Version
+					r.cipherSuite = dtls.CipherSuiteID(*serverHello.CipherSuiteID)
+				}
+			}
+		}
+	}
+
+	if outbound {
+		if newFragment {
+			r.inFlight = true
+		} else if retransmitted {
+			r.diagnostics.Retransmissions++
+		}
+	}
+}
+
+// dtlsHandshakeTypeName returns the name of a handshake message type.
+// pion/dtls names the Certificate message "TypeCertificate".
+func dtlsHandshakeTypeName(t handshake.Type) string {
+	return strings.TrimPrefix(t.String(), "Type")
+}
+
+// dtlsVersionName returns the name of a DTLS protocol version
+func dtlsVersionName(v protocol.Version) string {
+	switch {
+	case v.Equal(protocol.Version1_2):
+		return "DTLS 1.2"
+	case v.Equal(protocol.Version1_0):
+		return "DTLS 1.0"
+	default:
+		return ""
+	}
+}
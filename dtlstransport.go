@@ -4,6 +4,7 @@
 package webrtc
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -11,6 +12,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -40,6 +42,9 @@ type DTLSTransport struct {
 	remoteCertificate     []byte
 	state                 DTLSTransportState
 	srtpProtectionProfile srtp.ProtectionProfile
+	handshakeDuration     time.Duration
+	handshakeRole         DTLSRole
+	handshakeRecorder     *dtlsHandshakeRecorder
 
 	onStateChangeHandler func(DTLSTransportState)
 
@@ -52,6 +57,10 @@ type DTLSTransport struct {
 
 	dtlsMatcher mux.MatchFunc
 
+	// mux is only set when the DTLSTransport runs directly over a
+	// caller-supplied connection instead of an ICETransport
+	mux *mux.Mux
+
 	api *API
 	log logging.LeveledLogger
 }
@@ -69,27 +78,78 @@ func (api *API) NewDTLSTransport(transport *ICETransport, certificates []Certifi
 		log:          api.settingEngine.LoggerFactory.NewLogger("DTLSTransport"),
 	}
 
+	if err := t.setCertificates(certificates); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// NewDTLSTransportWithConn creates a new DTLSTransport that runs directly over
+// conn without ICE. This is useful for links that already have a reliable path,
+// such as server-to-server connections. The remote certificate is still verified
+// against the fingerprints passed to Start.
+//
+// ICE roles are not available to break the DTLS role tie, so either the remote
+// DTLSParameters passed to Start or SettingEngine.SetAnsweringDTLSRole must
+// select an explicit role.
+//
+// This constructor is part of the ORTC API. It is not
+// meant to be used together with the basic WebRTC API.
+func (api *API) NewDTLSTransportWithConn(conn net.Conn, certificates []Certificate) (*DTLSTransport, error) {
+	t := &DTLSTransport{
+		api:         api,
+		state:       DTLSTransportStateNew,
+		dtlsMatcher: mux.MatchDTLS,
+		srtpReady:   make(chan struct{}),
+		log:         api.settingEngine.LoggerFactory.NewLogger("DTLSTransport"),
+	}
+
+	if err := t.setCertificates(certificates); err != nil {
+		return nil, err
+	}
+
+	t.mux = mux.NewMux(mux.Config{
+		Conn:          conn,
+		BufferSize:    int(api.settingEngine.getReceiveMTU()),
+		LoggerFactory: api.settingEngine.LoggerFactory,
+	})
+
+	return t, nil
+}
+
+// NewDTLSTransportWithPacketConn creates a new DTLSTransport that runs directly over
+// conn without ICE, exchanging packets with raddr only. Packets received from any
+// other address are dropped. See NewDTLSTransportWithConn for details.
+//
+// This constructor is part of the ORTC API. It is not
+// meant to be used together with the basic WebRTC API.
+func (api *API) NewDTLSTransportWithPacketConn(conn net.PacketConn, raddr net.Addr, certificates []Certificate) (*DTLSTransport, error) {
+	return api.NewDTLSTransportWithConn(&packetConnWithRemote{PacketConn: conn, raddr: raddr}, certificates)
+}
+
+func (t *DTLSTransport) setCertificates(certificates []Certificate) error {
 	if len(certificates) > 0 {
 		now := time.Now()
 		for _, x509Cert := range certificates {
 			if !x509Cert.Expires().IsZero() && now.After(x509Cert.Expires()) {
-				return nil, &rtcerr.InvalidAccessError{Err: ErrCertificateExpired}
+				return &rtcerr.InvalidAccessError{Err: ErrCertificateExpired}
 			}
 			t.certificates = append(t.certificates, x509Cert)
 		}
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
-			return nil, &rtcerr.UnknownError{Err: err}
+			return &rtcerr.UnknownError{Err: err}
 		}
 		certificate, err := GenerateCertificate(sk)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		t.certificates = []Certificate{*certificate}
 	}
 
-	return t, nil
+	return nil
 }
 
 // ICETransport returns the currently-configured *ICETransport or nil
@@ -152,8 +212,13 @@ func (t *DTLSTransport) WriteRTCP(pkts []rtcp.Packet) (int, error) {
 func (t *DTLSTransport) GetLocalParameters() (DTLSParameters, error) {
 	fingerprints := []DTLSFingerprint{}
 
+	fingerprintAlgorithms := t.api.settingEngine.dtls.fingerprintAlgorithms
+	if len(fingerprintAlgorithms) == 0 {
+		fingerprintAlgorithms = []crypto.Hash{crypto.SHA256}
+	}
+
 	for _, c := range t.certificates {
-		prints, err := c.GetFingerprints()
+		prints, err := c.getFingerprints(fingerprintAlgorithms)
 		if err != nil {
 			return DTLSParameters{}, err
 		}
@@ -175,7 +240,159 @@ func (t *DTLSTransport) GetRemoteCertificate() []byte {
 	return t.remoteCertificate
 }
 
+// DTLSConnectionInfo describes an established DTLS connection
+type DTLSConnectionInfo struct {
+	// Version is the negotiated protocol version, e.g. "DTLS 1.2"
+	Version string
+
+	// CipherSuite is the IANA name of the negotiated cipher suite
+	CipherSuite string
+
+	// SRTPProtectionProfile is the IANA name of the negotiated SRTP protection profile
+	SRTPProtectionProfile string
+
+	// RemoteCertificates is the DER encoded certificate chain of the remote
+	RemoteCertificates [][]byte
+
+	// HandshakeDuration is the time from starting until completing the handshake
+	HandshakeDuration time.Duration
+
+	// Retransmissions is the number of flights that had to be sent again
+	// during the handshake
+	Retransmissions int
+}
+
+// GetConnectionInfo returns details of the negotiated DTLS connection.
+// It returns an error until the handshake has completed.
+func (t *DTLSTransport) GetConnectionInfo() (DTLSConnectionInfo, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.conn == nil {
+		return DTLSConnectionInfo{}, errDtlsTransportNotStarted
+	}
+
+	version, cipherSuite := t.handshakeRecorder.negotiated()
+	connState := t.conn.ConnectionState()
+	return DTLSConnectionInfo{
+		Version:               dtlsVersionName(version),
+		CipherSuite:           dtls.CipherSuiteName(cipherSuite),
+		SRTPProtectionProfile: srtpProtectionProfileName(t.srtpProtectionProfile),
+		RemoteCertificates:    connState.PeerCertificates,
+		HandshakeDuration:     t.handshakeDuration,
+		Retransmissions:       t.handshakeRecorder.getDiagnostics().Retransmissions,
+	}, nil
+}
+
+// GetHandshakeDiagnostics returns what was seen of the last DTLS handshake,
+// including one that failed or is still in progress.
+func (t *DTLSTransport) GetHandshakeDiagnostics() DTLSHandshakeDiagnostics {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.handshakeRecorder == nil {
+		return DTLSHandshakeDiagnostics{}
+	}
+	return t.handshakeRecorder.getDiagnostics()
+}
+
+func srtpProtectionProfileName(profile srtp.ProtectionProfile) string {
+	switch profile {
+	case srtp.ProtectionProfileAes128CmHmacSha1_80:
+		return "SRTP_AES128_CM_HMAC_SHA1_80"
+	case srtp.ProtectionProfileAes128CmHmacSha1_32:
+		return "SRTP_AES128_CM_HMAC_SHA1_32"
+	case srtp.ProtectionProfileAeadAes128Gcm:
+		return "SRTP_AEAD_AES_128_GCM"
+	default:
+		return ""
+	}
+}
+
+func (t *DTLSTransport) populateTransportStats(stats *TransportStats) {
+	stats.DTLSState = t.State()
+
+	if info, err := t.GetConnectionInfo(); err == nil {
+		stats.DTLSCipher = info.CipherSuite
+		stats.SRTPCipher = info.SRTPProtectionProfile
+	}
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.conn != nil {
+		version, _ := t.handshakeRecorder.negotiated()
+		stats.TLSVersion = fmt.Sprintf("%02X%02X", version.Major, version.Minor)
+	}
+}
+
+// ExportKeyingMaterial derives length bytes of keying material from the
+// DTLS session as described in RFC 5705. Both peers derive the same bytes for
+// the same label. Labels reserved by TLS, and a non-empty context which the
+// DTLS implementation does not support yet, return an error.
+func (t *DTLSTransport) ExportKeyingMaterial(label string, context []byte, length int) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.conn == nil {
+		return nil, errDtlsTransportNotStarted
+	}
+
+	connState := t.conn.ConnectionState()
+	return connState.ExportKeyingMaterial(label, context, length)
+}
+
 func (t *DTLSTransport) startSRTP() error {
+	srtpConfig := t.newSRTPConfig()
+
+	connState := t.conn.ConnectionState()
+	err := srtpConfig.ExtractSessionKeysFromDTLS(&connState, t.role() == DTLSRoleClient)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errDtlsKeyExtractionFailed, err)
+	}
+
+	return t.startSRTPSessions(srtpConfig)
+}
+
+// startSDES starts SRTP with keys exchanged in the a=crypto attributes
+// (RFC 4568) instead of running a DTLS handshake. There is no DTLS
+// association, the transport still reports DTLSTransportStateConnected once
+// SRTP is keyed so the PeerConnectionState is derived the same way as with
+// DTLS. GetConnectionInfo and GetRemoteCertificate have nothing to return.
+func (t *DTLSTransport) startSDES(local, remote sdesCrypto) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.ensureICEConn(); err != nil {
+		return err
+	}
+
+	if t.state != DTLSTransportStateNew {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("%w: %s", errInvalidDTLSStart, t.state)}
+	}
+
+	t.srtpEndpoint = t.newEndpoint(mux.MatchSRTP)
+	t.srtcpEndpoint = t.newEndpoint(mux.MatchSRTCP)
+	t.srtpProtectionProfile = local.suite.profile
+
+	srtpConfig := t.newSRTPConfig()
+	keyLen := local.suite.keyLen
+	srtpConfig.Keys = srtp.SessionKeys{
+		LocalMasterKey:   local.keySalt[:keyLen],
+		LocalMasterSalt:  local.keySalt[keyLen:],
+		RemoteMasterKey:  remote.keySalt[:keyLen],
+		RemoteMasterSalt: remote.keySalt[keyLen:],
+	}
+
+	if err := t.startSRTPSessions(srtpConfig); err != nil {
+		t.onStateChange(DTLSTransportStateFailed)
+		return err
+	}
+
+	t.onStateChange(DTLSTransportStateConnected)
+	return nil
+}
+
+func (t *DTLSTransport) newSRTPConfig() *srtp.Config {
 	srtpConfig := &srtp.Config{
 		Profile:       t.srtpProtectionProfile,
 		BufferFactory: t.api.settingEngine.BufferFactory,
@@ -209,12 +426,10 @@ func (t *DTLSTransport) startSRTP() error {
 		)
 	}
 
-	connState := t.conn.ConnectionState()
-	err := srtpConfig.ExtractSessionKeysFromDTLS(&connState, t.role() == DTLSRoleClient)
-	if err != nil {
-		return fmt.Errorf("%w: %v", errDtlsKeyExtractionFailed, err)
-	}
+	return srtpConfig
+}
 
+func (t *DTLSTransport) startSRTPSessions(srtpConfig *srtp.Config) error {
 	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("%w: %v", errFailedToStartSRTP, err)
@@ -231,8 +446,24 @@ func (t *DTLSTransport) startSRTP() error {
 	return nil
 }
 
+// srtpProtectionProfileFromDTLS maps a profile negotiated by DTLS to the SRTP
+// implementation. It returns false for profiles srtp can't be started with,
+// the AES-256 profiles are not implemented by pion/srtp v2.
+func srtpProtectionProfileFromDTLS(profile dtls.SRTPProtectionProfile) (srtp.ProtectionProfile, bool) {
+	switch profile {
+	case dtls.SRTP_AEAD_AES_128_GCM:
+		return srtp.ProtectionProfileAeadAes128Gcm, true
+	case dtls.SRTP_AES128_CM_HMAC_SHA1_80:
+		return srtp.ProtectionProfileAes128CmHmacSha1_80, true
+	case dtls.SRTP_AES128_CM_HMAC_SHA1_32:
+		return srtp.ProtectionProfileAes128CmHmacSha1_32, true
+	default:
+		return 0, false
+	}
+}
+
 func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
-	if value, ok := t.srtpSession.Load().(*srtp.SessionSRTP); ok {
+	if value, ok := t.srtpSession.Load().(*srtp.SessionSRTP); ok && value != nil {
 		return value, nil
 	}
 
@@ -240,13 +471,22 @@ func (t *DTLSTransport) getSRTPSession() (*srtp.SessionSRTP, error) {
 }
 
 func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
-	if value, ok := t.srtcpSession.Load().(*srtp.SessionSRTCP); ok {
+	if value, ok := t.srtcpSession.Load().(*srtp.SessionSRTCP); ok && value != nil {
 		return value, nil
 	}
 
 	return nil, errDtlsTransportNotStarted
 }
 
+// srtpReadyChan returns the channel that is closed once the SRTP sessions
+// are available. A DTLS restart replaces it.
+func (t *DTLSTransport) srtpReadyChan() chan struct{} {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.srtpReady
+}
+
 func (t *DTLSTransport) role() DTLSRole {
 	// If remote has an explicit role use the inverse
 	switch t.remoteParameters.Role {
@@ -267,6 +507,9 @@ func (t *DTLSTransport) role() DTLSRole {
 	}
 
 	// Remote was auto and no explicit role was configured via SettingEngine
+	if t.iceTransport == nil {
+		return DTLSRoleAuto
+	}
 	if t.iceTransport.Role() == ICERoleControlling {
 		return DTLSRoleServer
 	}
@@ -289,39 +532,60 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 			return DTLSRole(0), nil, &rtcerr.InvalidStateError{Err: fmt.Errorf("%w: %s", errInvalidDTLSStart, t.state)}
 		}
 
-		t.srtpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTP)
-		t.srtcpEndpoint = t.iceTransport.newEndpoint(mux.MatchSRTCP)
 		t.remoteParameters = remoteParameters
+		role := t.role()
+		if role == DTLSRoleAuto {
+			return DTLSRole(0), nil, errDTLSTransportRoleAuto
+		}
+
+		t.srtpEndpoint = t.newEndpoint(mux.MatchSRTP)
+		t.srtcpEndpoint = t.newEndpoint(mux.MatchSRTCP)
+
+		// Without SNI DTLS presents the first certificate, the others are
+		// only advertised through their fingerprints
+		certificates := make([]tls.Certificate, 0, len(t.certificates))
+		for _, cert := range t.certificates {
+			certificates = append(certificates, tls.Certificate{
+				Certificate: [][]byte{cert.x509Cert.Raw},
+				PrivateKey:  cert.privateKey,
+			})
+		}
+
+		// Only offer the profiles SRTP can be started with
+		srtpProtectionProfiles := []dtls.SRTPProtectionProfile{}
+		if len(t.api.settingEngine.srtpProtectionProfiles) == 0 {
+			srtpProtectionProfiles = defaultSrtpProtectionProfiles()
+		}
+		for _, profile := range t.api.settingEngine.srtpProtectionProfiles {
+			if _, ok := srtpProtectionProfileFromDTLS(profile); ok {
+				srtpProtectionProfiles = append(srtpProtectionProfiles, profile)
+			}
+		}
+		if len(srtpProtectionProfiles) == 0 {
+			t.onStateChange(DTLSTransportStateFailed)
+			return DTLSRole(0), nil, errNoSupportedSRTPProtectionProfile
+		}
 
-		cert := t.certificates[0]
 		t.onStateChange(DTLSTransportStateConnecting)
 
-		return t.role(), &dtls.Config{
-			Certificates: []tls.Certificate{
-				{
-					Certificate: [][]byte{cert.x509Cert.Raw},
-					PrivateKey:  cert.privateKey,
-				},
-			},
-			SRTPProtectionProfiles: func() []dtls.SRTPProtectionProfile {
-				if len(t.api.settingEngine.srtpProtectionProfiles) > 0 {
-					return t.api.settingEngine.srtpProtectionProfiles
-				}
-
-				return defaultSrtpProtectionProfiles()
-			}(),
-			ClientAuth:         dtls.RequireAnyClientCert,
-			LoggerFactory:      t.api.settingEngine.LoggerFactory,
-			InsecureSkipVerify: true,
+		return role, &dtls.Config{
+			Certificates:           certificates,
+			SRTPProtectionProfiles: srtpProtectionProfiles,
+			ClientAuth:             dtls.RequireAnyClientCert,
+			LoggerFactory:          t.api.settingEngine.LoggerFactory,
+			InsecureSkipVerify:     true,
 		}, nil
 	}
 
 	var dtlsConn *dtls.Conn
-	dtlsEndpoint := t.iceTransport.newEndpoint(mux.MatchDTLS)
 	role, dtlsConfig, err := prepareTransport()
 	if err != nil {
 		return err
 	}
+	dtlsEndpoint := newDTLSHandshakeRecorder(t.newEndpoint(mux.MatchDTLS))
+	t.lock.Lock()
+	t.handshakeRecorder = dtlsEndpoint
+	t.lock.Unlock()
 
 	if t.api.settingEngine.replayProtection.DTLS != nil {
 		dtlsConfig.ReplayProtectionWindow = int(*t.api.settingEngine.replayProtection.DTLS)
@@ -331,21 +595,33 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		dtlsConfig.FlightInterval = t.api.settingEngine.dtls.retransmissionInterval
 	}
 
+	if verify := t.api.settingEngine.dtls.verifyPeerCertificate; verify != nil {
+		fingerprints := append([]DTLSFingerprint{}, remoteParameters.Fingerprints...)
+		dtlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verify(rawCerts, fingerprints)
+		}
+	}
+
 	// Connect as DTLS Client/Server, function is blocking and we
 	// must not hold the DTLSTransport lock
+	handshakeStart := time.Now()
 	if role == DTLSRoleClient {
 		dtlsConn, err = dtls.Client(dtlsEndpoint, dtlsConfig)
 	} else {
 		dtlsConn, err = dtls.Server(dtlsEndpoint, dtlsConfig)
 	}
 
+	dtlsEndpoint.stop()
+
 	// Re-take the lock, nothing beyond here is blocking
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
 	if err != nil {
+		handshakeErr := &DTLSHandshakeError{Err: err, Diagnostics: dtlsEndpoint.getDiagnostics()}
+		t.log.Warn(handshakeErr.Error())
 		t.onStateChange(DTLSTransportStateFailed)
-		return err
+		return handshakeErr
 	}
 
 	srtpProfile, ok := dtlsConn.SelectedSRTPProtectionProfile()
@@ -354,12 +630,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		return ErrNoSRTPProtectionProfile
 	}
 
-	switch srtpProfile {
-	case dtls.SRTP_AEAD_AES_128_GCM:
-		t.srtpProtectionProfile = srtp.ProtectionProfileAeadAes128Gcm
-	case dtls.SRTP_AES128_CM_HMAC_SHA1_80:
-		t.srtpProtectionProfile = srtp.ProtectionProfileAes128CmHmacSha1_80
-	default:
+	if t.srtpProtectionProfile, ok = srtpProtectionProfileFromDTLS(srtpProfile); !ok {
 		t.onStateChange(DTLSTransportStateFailed)
 		return ErrNoSRTPProtectionProfile
 	}
@@ -383,7 +654,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 			return err
 		}
 
-		if err = t.validateFingerPrint(parsedRemoteCert); err != nil {
+		if err = validateFingerPrint(parsedRemoteCert, t.remoteParameters.Fingerprints); err != nil {
 			if closeErr := dtlsConn.Close(); closeErr != nil {
 				t.log.Error(err.Error())
 			}
@@ -394,6 +665,8 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	}
 
 	t.conn = dtlsConn
+	t.handshakeDuration = time.Since(handshakeStart)
+	t.handshakeRole = role
 	t.onStateChange(DTLSTransportStateConnected)
 
 	return t.startSRTP()
@@ -425,15 +698,156 @@ func (t *DTLSTransport) Stop() error {
 			closeErrs = append(closeErrs, err)
 		}
 	}
+	if t.mux != nil {
+		closeErrs = append(closeErrs, t.mux.Close())
+	}
+
 	t.onStateChange(DTLSTransportStateClosed)
 	return util.FlattenErrs(closeErrs)
 }
 
-func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error {
-	for _, fp := range t.remoteParameters.Fingerprints {
+// associationRole returns the role of the last DTLS association, or
+// DTLSRoleAuto if there was none. A restart keeps this role unless the
+// remote picks another one.
+func (t *DTLSTransport) associationRole() DTLSRole {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.handshakeRole == DTLSRole(0) {
+		return DTLSRoleAuto
+	}
+	return t.handshakeRole
+}
+
+// needsRestart reports whether a new DTLS association is required for the
+// remote parameters of a renegotiation. That is the case if the remote
+// certificate doesn't match the fingerprints anymore, or if the remote
+// switched between two explicit roles.
+func (t *DTLSTransport) needsRestart(remoteParameters DTLSParameters) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	// SDES keyed transports have no DTLS association to restart
+	if t.state != DTLSTransportStateConnected || t.conn == nil {
+		return false
+	}
+
+	if remoteParameters.Role != DTLSRoleAuto && t.remoteParameters.Role != DTLSRoleAuto && remoteParameters.Role != t.remoteParameters.Role {
+		return true
+	}
+
+	// Without fingerprint verification the certificate may never have
+	// matched, a restart is needed if the signaled fingerprints changed
+	if t.api.settingEngine.disableCertificateFingerprintVerification {
+		return !sameDTLSFingerprints(t.remoteParameters.Fingerprints, remoteParameters.Fingerprints)
+	}
+
+	remoteCert, err := x509.ParseCertificate(t.remoteCertificate)
+	if err != nil {
+		return true
+	}
+	return validateFingerPrint(remoteCert, remoteParameters.Fingerprints) != nil
+}
+
+// stopForRestart closes the DTLS association and the SRTP sessions so Start
+// can create a new association. It returns the remote parameters to start
+// with, the current role is kept unless the remote picked one. Once it
+// returns, srtpReadyChan blocks until the new SRTP sessions exist. Streams
+// of the previous SRTP sessions are left open so RTPSenders and RTPReceivers
+// can move their readers to the new sessions.
+func (t *DTLSTransport) stopForRestart(remoteParameters DTLSParameters) (DTLSParameters, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.state != DTLSTransportStateConnected || t.conn == nil {
+		return remoteParameters, &rtcerr.InvalidStateError{Err: fmt.Errorf("%w: %s", errInvalidDTLSRestart, t.state)}
+	}
+
+	if remoteParameters.Role == DTLSRoleAuto {
+		remoteParameters.Role = DTLSRoleClient
+		if t.handshakeRole == DTLSRoleClient {
+			remoteParameters.Role = DTLSRoleServer
+		}
+	}
+
+	closeErrs := t.clearSRTPSessions()
+	if err := t.conn.Close(); err != nil && !errors.Is(err, dtls.ErrConnClosed) {
+		closeErrs = append(closeErrs, err)
+	}
+
+	t.conn = nil
+	t.remoteCertificate = nil
+	t.state = DTLSTransportStateNew
+
+	if err := util.FlattenErrs(closeErrs); err != nil {
+		t.log.Warnf("Failed to close DTLS association before restart: %s", err)
+	}
+	return remoteParameters, nil
+}
+
+// stopSDES closes the SRTP sessions keyed with SDES so startSDES can key new
+// ones, e.g. after the remote sent a new a=crypto attribute in a re-offer.
+// Like stopForRestart it leaves the streams of the previous sessions open.
+func (t *DTLSTransport) stopSDES() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.state != DTLSTransportStateConnected || t.conn != nil {
+		return &rtcerr.InvalidStateError{Err: fmt.Errorf("%w: %s", errInvalidSDESRekey, t.state)}
+	}
+
+	t.state = DTLSTransportStateNew
+	if err := util.FlattenErrs(t.clearSRTPSessions()); err != nil {
+		t.log.Warnf("Failed to close SRTP sessions before rekeying: %s", err)
+	}
+	return nil
+}
+
+// clearSRTPSessions closes the SRTP sessions, srtpReadyChan blocks until new
+// sessions are started. The caller must hold the lock.
+func (t *DTLSTransport) clearSRTPSessions() []error {
+	var closeErrs []error
+	if srtpSession, err := t.getSRTPSession(); err == nil {
+		closeErrs = append(closeErrs, srtpSession.Close())
+	}
+	if srtcpSession, err := t.getSRTCPSession(); err == nil {
+		closeErrs = append(closeErrs, srtcpSession.Close())
+	}
+
+	t.srtpSession.Store((*srtp.SessionSRTP)(nil))
+	t.srtcpSession.Store((*srtp.SessionSRTCP)(nil))
+	t.srtpReady = make(chan struct{})
+	return closeErrs
+}
+
+// sameDTLSFingerprints compares two sets of fingerprints regardless of their
+// order and of the case of the values
+func sameDTLSFingerprints(a, b []DTLSFingerprint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, fa := range a {
+		found := false
+		for _, fb := range b {
+			if strings.EqualFold(fa.Algorithm, fb.Algorithm) && strings.EqualFold(fa.Value, fb.Value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func validateFingerPrint(remoteCert *x509.Certificate, fingerprints []DTLSFingerprint) error {
+	for _, fp := range fingerprints {
 		hashAlgo, err := fingerprint.HashFromString(fp.Algorithm)
 		if err != nil {
-			return err
+			// Unknown algorithms are ignored as long as another fingerprint matches
+			continue
 		}
 
 		remoteValue, err := fingerprint.Fingerprint(remoteCert, hashAlgo)
@@ -450,13 +864,21 @@ func (t *DTLSTransport) validateFingerPrint(remoteCert *x509.Certificate) error
 }
 
 func (t *DTLSTransport) ensureICEConn() error {
-	if t.iceTransport == nil {
+	if t.iceTransport == nil && t.mux == nil {
 		return errICEConnectionNotStarted
 	}
 
 	return nil
 }
 
+func (t *DTLSTransport) newEndpoint(f mux.MatchFunc) *mux.Endpoint {
+	if t.mux != nil {
+		return t.mux.NewEndpoint(f)
+	}
+
+	return t.iceTransport.newEndpoint(f)
+}
+
 func (t *DTLSTransport) storeSimulcastStream(s *srtp.ReadStreamSRTP) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
@@ -497,3 +919,31 @@ func (t *DTLSTransport) streamsForSSRC(ssrc SSRC, streamInfo interceptor.StreamI
 
 	return rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, nil
 }
+
+// packetConnWithRemote adapts a net.PacketConn to a net.Conn that only
+// exchanges packets with a single remote address
+type packetConnWithRemote struct {
+	net.PacketConn
+	raddr net.Addr
+}
+
+func (c *packetConnWithRemote) Read(b []byte) (int, error) {
+	for {
+		n, addr, err := c.ReadFrom(b)
+		if err != nil {
+			return n, err
+		}
+
+		if addr.String() == c.raddr.String() {
+			return n, nil
+		}
+	}
+}
+
+func (c *packetConnWithRemote) Write(b []byte) (int, error) {
+	return c.WriteTo(b, c.raddr)
+}
+
+func (c *packetConnWithRemote) RemoteAddr() net.Addr {
+	return c.raddr
+}
@@ -4,10 +4,23 @@
 package webrtc
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/handshake"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/srtp/v2"
 	"github.com/pion/transport/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -131,3 +144,282 @@ func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
 		runTest(DTLSRoleClient)
 	})
 }
+
+func TestPeerConnection_DTLSFingerprintAlgorithms(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecdsaCert, err := GenerateCertificate(ecdsaKey)
+	assert.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaCert, err := GenerateCertificate(rsaKey)
+	assert.NoError(t, err)
+
+	s := SettingEngine{}
+	s.SetDTLSFingerprintAlgorithms(crypto.SHA384, crypto.SHA512)
+
+	offerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{
+		Certificates: []Certificate{*ecdsaCert, *rsaCert},
+	})
+	assert.NoError(t, err)
+
+	answerPC, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	connectionComplete := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+
+	// Every certificate is advertised with every configured algorithm
+	offerSDP := offerPC.LocalDescription().SDP
+	for _, cert := range []*Certificate{ecdsaCert, rsaCert} {
+		fingerprints, err := cert.getFingerprints([]crypto.Hash{crypto.SHA384, crypto.SHA512})
+		assert.NoError(t, err)
+		for _, f := range fingerprints {
+			assert.Contains(t, offerSDP, "a=fingerprint:"+f.Algorithm+" "+strings.ToUpper(f.Value))
+		}
+	}
+	assert.NotContains(t, offerSDP, "a=fingerprint:sha-256")
+
+	connectionComplete.Wait()
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDTLSTransport_ExportKeyingMaterial(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.SCTP().Transport().ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.ErrorIs(t, err, errDtlsTransportNotStarted)
+
+	connectionComplete := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connectionComplete.Wait()
+
+	offerKey, err := offerPC.SCTP().Transport().ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.NoError(t, err)
+	assert.Len(t, offerKey, 32)
+
+	answerKey, err := answerPC.SCTP().Transport().ExportKeyingMaterial("EXPORTER-test", nil, 32)
+	assert.NoError(t, err)
+	assert.Equal(t, offerKey, answerKey)
+
+	otherKey, err := answerPC.SCTP().Transport().ExportKeyingMaterial("EXPORTER-other", nil, 32)
+	assert.NoError(t, err)
+	assert.NotEqual(t, offerKey, otherKey)
+
+	_, err = offerPC.SCTP().Transport().ExportKeyingMaterial("master secret", nil, 32)
+	assert.Error(t, err)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPeerConnection_SRTPProtectionProfiles(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	// The AES-256 profile isn't supported by SRTP and must not be negotiated
+	s.SetSRTPProtectionProfiles(dtls.SRTP_AEAD_AES_256_GCM, dtls.SRTP_AES128_CM_HMAC_SHA1_32)
+
+	offerPC, answerPC, err := NewAPI(WithSettingEngine(s)).newPair(Configuration{})
+	assert.NoError(t, err)
+
+	connectionComplete := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connectionComplete.Wait()
+
+	for _, pc := range []*PeerConnection{offerPC, answerPC} {
+		pc.dtlsTransport.lock.RLock()
+		assert.Equal(t, srtp.ProtectionProfileAes128CmHmacSha1_32, pc.dtlsTransport.srtpProtectionProfile)
+		pc.dtlsTransport.lock.RUnlock()
+	}
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestPeerConnection_NoSupportedSRTPProtectionProfile(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetSRTPProtectionProfiles(dtls.SRTP_AEAD_AES_256_GCM)
+
+	offerPC, answerPC, err := NewAPI(WithSettingEngine(s)).newPair(Configuration{})
+	assert.NoError(t, err)
+
+	dtlsFailed, dtlsFailedFunc := context.WithCancel(context.Background())
+	offerPC.SCTP().Transport().OnStateChange(func(state DTLSTransportState) {
+		if state == DTLSTransportStateFailed {
+			dtlsFailedFunc()
+		}
+	})
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-dtlsFailed.Done()
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+func TestDTLSTransport_GetConnectionInfo(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.SCTP().Transport().GetConnectionInfo()
+	assert.ErrorIs(t, err, errDtlsTransportNotStarted)
+
+	connectionComplete := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	connectionComplete.Wait()
+
+	offerInfo, err := offerPC.SCTP().Transport().GetConnectionInfo()
+	assert.NoError(t, err)
+	answerInfo, err := answerPC.SCTP().Transport().GetConnectionInfo()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "DTLS 1.2", offerInfo.Version)
+	assert.Equal(t, offerInfo.Version, answerInfo.Version)
+	assert.Equal(t, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", offerInfo.CipherSuite)
+	assert.Equal(t, offerInfo.CipherSuite, answerInfo.CipherSuite)
+	assert.Equal(t, "SRTP_AEAD_AES_128_GCM", offerInfo.SRTPProtectionProfile)
+	assert.Equal(t, offerInfo.SRTPProtectionProfile, answerInfo.SRTPProtectionProfile)
+	assert.Equal(t, [][]byte{answerPC.configuration.Certificates[0].x509Cert.Raw}, offerInfo.RemoteCertificates)
+	assert.NotZero(t, offerInfo.HandshakeDuration)
+
+	transportStats, ok := offerPC.GetStats()["iceTransport"].(TransportStats)
+	assert.True(t, ok)
+	assert.Equal(t, DTLSTransportStateConnected, transportStats.DTLSState)
+	assert.Equal(t, offerInfo.CipherSuite, transportStats.DTLSCipher)
+	assert.Equal(t, offerInfo.SRTPProtectionProfile, transportStats.SRTPCipher)
+	assert.Equal(t, "FEFD", transportStats.TLSVersion)
+
+	closePairNow(t, offerPC, answerPC)
+}
+
+var errUntrustedDeviceCertificate = errors.New("untrusted device certificate")
+
+func TestPeerConnection_DTLSVerifyPeerCertificate(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	t.Run("Accept", func(t *testing.T) {
+		var verifiedCerts [][]byte
+		var verifiedFingerprints []DTLSFingerprint
+
+		s := SettingEngine{}
+		s.SetDTLSVerifyPeerCertificate(func(rawCerts [][]byte, fingerprints []DTLSFingerprint) error {
+			verifiedCerts, verifiedFingerprints = rawCerts, fingerprints
+			return nil
+		})
+
+		offerPC, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		connectionComplete := untilConnectionState(PeerConnectionStateConnected, offerPC, answerPC)
+		assert.NoError(t, signalPair(offerPC, answerPC))
+		connectionComplete.Wait()
+
+		offerCertificate := offerPC.configuration.Certificates[0]
+		assert.Equal(t, [][]byte{offerCertificate.x509Cert.Raw}, verifiedCerts)
+
+		offerFingerprints, err := offerCertificate.GetFingerprints()
+		assert.NoError(t, err)
+		assert.Equal(t, offerFingerprints[0].Algorithm, verifiedFingerprints[0].Algorithm)
+		assert.True(t, strings.EqualFold(offerFingerprints[0].Value, verifiedFingerprints[0].Value))
+
+		closePairNow(t, offerPC, answerPC)
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		s := SettingEngine{}
+		s.SetDTLSVerifyPeerCertificate(func([][]byte, []DTLSFingerprint) error {
+			return errUntrustedDeviceCertificate
+		})
+
+		offerPC, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		answerPC, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		connectionFailed := untilConnectionState(PeerConnectionStateFailed, offerPC, answerPC)
+		assert.NoError(t, signalPair(offerPC, answerPC))
+		connectionFailed.Wait()
+
+		assert.Nil(t, answerPC.SCTP().Transport().conn)
+
+		diagnostics := answerPC.SCTP().Transport().GetHandshakeDiagnostics()
+		assert.Equal(t, "ServerHelloDone", diagnostics.LastReceivedMessage)
+		assert.Equal(t, "Alert Fatal: BadCertificate", diagnostics.SentAlert)
+		assert.Equal(t, diagnostics.SentAlert, offerPC.SCTP().Transport().GetHandshakeDiagnostics().ReceivedAlert)
+
+		closePairNow(t, offerPC, answerPC)
+	})
+}
+
+func TestDTLSHandshakeRecorder_Retransmissions(t *testing.T) {
+	datagram := func(messageSequences ...uint16) []byte {
+		var out []byte
+		for _, seq := range messageSequences {
+			hh := handshake.Header{Type: handshake.TypeCertificate, MessageSequence: seq}
+			content, err := hh.Marshal()
+			assert.NoError(t, err)
+
+			h := recordlayer.Header{
+				ContentType: protocol.ContentTypeHandshake,
+				ContentLen:  uint16(len(content)),
+				Version:     protocol.Version1_2,
+			}
+			header, err := h.Marshal()
+			assert.NoError(t, err)
+			out = append(out, append(header, content...)...)
+		}
+		return out
+	}
+
+	r := newDTLSHandshakeRecorder(nil)
+
+	// One flight over two datagrams, then sent again
+	r.inspect(datagram(0, 1), true)
+	r.inspect(datagram(2), true)
+	r.inspect(datagram(0, 1), true)
+	r.inspect(datagram(2), true)
+	assert.Equal(t, 1, r.getDiagnostics().Retransmissions)
+
+	// The answer starts the next flight
+	r.inspect(datagram(0), false)
+	r.inspect(datagram(3), true)
+	assert.Equal(t, 1, r.getDiagnostics().Retransmissions)
+	r.inspect(datagram(3), true)
+	r.inspect(datagram(3), true)
+	assert.Equal(t, 3, r.getDiagnostics().Retransmissions)
+	assert.Equal(t, "Certificate", r.getDiagnostics().LastSentMessage)
+}
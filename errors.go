@@ -62,6 +62,15 @@ var (
 	// specified for a data channel has been exceeded.
 	ErrMaxDataChannelID = errors.New("maximum number ID for datachannel specified")
 
+	// ErrDataChannelLayoutDuplicateID indicates that a DataChannelLayout assigns
+	// the same ID to more than one data channel, or to a data channel that
+	// already exists.
+	ErrDataChannelLayoutDuplicateID = errors.New("data channel layout contains a duplicate ID")
+
+	// ErrDataChannelLayoutRoleMismatch indicates that a DataChannelLayout entry
+	// pins an ID that doesn't match the parity of its DTLS role.
+	ErrDataChannelLayoutRoleMismatch = errors.New("data channel layout ID does not match DTLS role parity")
+
 	// ErrNegotiatedWithoutID indicates that an attempt to create a data channel
 	// was made while setting the negotiated option to true without providing
 	// the negotiated channel ID.
@@ -123,6 +132,10 @@ var (
 	// contains multiple conflicting ice-pwd values
 	ErrSessionDescriptionConflictingIcePwd = errors.New("SetRemoteDescription called with multiple conflicting ice-pwd values")
 
+	// ErrSessionDescriptionNoSDESCrypto indicates SetRemoteDescription was called with SDES-SRTP enabled and
+	// a SessionDescription without a supported a=crypto attribute
+	ErrSessionDescriptionNoSDESCrypto = errors.New("SetRemoteDescription called with no supported a=crypto attribute")
+
 	// ErrNoSRTPProtectionProfile indicates that the DTLS handshake completed and no SRTP Protection Profile was chosen
 	ErrNoSRTPProtectionProfile = errors.New("DTLS Handshake completed and no SRTP Protection Profile was chosen")
 
@@ -161,12 +174,16 @@ var (
 	errDetachBeforeOpened               = errors.New("datachannel not opened yet, try calling Detach from OnOpen")
 	errDtlsTransportNotStarted          = errors.New("the DTLS transport has not started yet")
 	errDtlsKeyExtractionFailed          = errors.New("failed extracting keys from DTLS for SRTP")
+	errNoSupportedSRTPProtectionProfile = errors.New("none of the configured SRTP Protection Profiles is supported")
 	errFailedToStartSRTP                = errors.New("failed to start SRTP")
 	errFailedToStartSRTCP               = errors.New("failed to start SRTCP")
 	errInvalidDTLSStart                 = errors.New("attempted to start DTLSTransport that is not in new state")
+	errInvalidDTLSRestart               = errors.New("attempted to restart DTLSTransport that is not connected")
+	errInvalidSDESRekey                 = errors.New("attempted to rekey SRTP of a DTLSTransport that is not connected with SDES")
 	errNoRemoteCertificate              = errors.New("peer didn't provide certificate via DTLS")
 	errIdentityProviderNotImplemented   = errors.New("identity provider is not implemented")
 	errNoMatchingCertificateFingerprint = errors.New("remote certificate does not match any fingerprint")
+	errDTLSTransportRoleAuto            = errors.New("DTLSTransport without ICETransport requires an explicit DTLS role")
 
 	errICEConnectionNotStarted        = errors.New("ICE connection not started")
 	errICECandidateTypeUnknown        = errors.New("unknown candidate type")
@@ -177,6 +194,8 @@ var (
 	errICEProtocolUnknown             = errors.New("unknown protocol")
 	errICEGathererNotStarted          = errors.New("gatherer not started")
 
+	errICEServerModeNoAddress = errors.New("ICEServerMode requires a UDP or TCP address")
+
 	errNetworkTypeUnknown = errors.New("unknown network type")
 
 	errSDPDoesNotMatchOffer                           = errors.New("new sdp does not match previous offer")
@@ -221,6 +240,7 @@ var (
 	errRTPTransceiverCodecUnsupported       = errors.New("unsupported codec type by this transceiver")
 
 	errSCTPTransportDTLS = errors.New("DTLS not established")
+	errSDESDataChannel   = errors.New("DataChannels require DTLS and are not available with SDES-SRTP")
 
 	errSDPZeroTransceivers                 = errors.New("addTransceiverSDP() called with 0 transceivers")
 	errSDPMediaSectionMediaDataChanInvalid = errors.New("invalid Media Section. Media + DataChannel both enabled")
@@ -238,9 +258,12 @@ var (
 
 	errICETransportNotInNew = errors.New("ICETransport can only be called in ICETransportStateNew")
 
-	errCertificatePEMFormatError = errors.New("bad Certificate PEM format")
+	errCertificatePEMFormatError        = errors.New("bad Certificate PEM format")
+	errCertificateRotatorExpiresTooSoon = errors.New("generated certificate expires within RotateBefore")
 
 	errRTPTooShort = errors.New("not long enough to be a RTP Packet")
 
+	errFrameTransformUnsupportedCodec = errors.New("frames of this codec can't be transformed")
+
 	errExcessiveRetries = errors.New("excessive retries in CreateOffer")
 )
@@ -0,0 +1,315 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+// FrameTransform is run on every encoded frame, e.g. sframe.Context.Encrypt
+// on a RTPSender and sframe.Context.Decrypt on a RTPReceiver.
+//
+// The codec specific RTP payload formats can't be used for transformed
+// frames, H264 for example would be split on start codes found in the
+// ciphertext. Transformed frames are therefore sent with a codec independent
+// payload format instead: every payload starts with one byte, its first bit is
+// set on the first packet of a frame and its second bit on the last one. Both
+// peers need a FrameTransform on the matching RTPSender and RTPReceiver.
+type FrameTransform func(frame []byte) ([]byte, error)
+
+const (
+	frameDescriptorStart = 0x80
+	frameDescriptorEnd   = 0x40
+
+	// frameTransformMaxLate is how many packets a RTPReceiver waits for a
+	// missing packet of a transformed frame
+	frameTransformMaxLate = 64
+)
+
+// framePayloads splits a transformed frame into payloads of at most mtu bytes
+func framePayloads(mtu int, frame []byte) [][]byte {
+	chunk := mtu - 1
+	if chunk < 1 {
+		return nil
+	}
+
+	payloads := [][]byte{}
+	for offset := 0; offset == 0 || offset < len(frame); offset += chunk {
+		end := offset + chunk
+		if end > len(frame) {
+			end = len(frame)
+		}
+
+		descriptor := byte(0)
+		if offset == 0 {
+			descriptor |= frameDescriptorStart
+		}
+		if end == len(frame) {
+			descriptor |= frameDescriptorEnd
+		}
+		payloads = append(payloads, append([]byte{descriptor}, frame[offset:end]...))
+	}
+	return payloads
+}
+
+// frameDepacketizer reads the payload format of transformed frames
+type frameDepacketizer struct{}
+
+func (frameDepacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	if len(payload) < 1 {
+		return nil, errRTPTooShort
+	}
+	return payload[1:], nil
+}
+
+func (frameDepacketizer) IsPartitionHead(payload []byte) bool {
+	return len(payload) > 0 && payload[0]&frameDescriptorStart != 0
+}
+
+func (frameDepacketizer) IsPartitionTail(_ bool, payload []byte) bool {
+	return len(payload) > 0 && payload[0]&frameDescriptorEnd != 0
+}
+
+// depacketizerForFrameTransform returns the depacketizer that assembles the
+// frames of codec. Audio packets carry one frame each and need none.
+func depacketizerForFrameTransform(kind RTPCodecType, codec RTPCodecCapability) (rtp.Depacketizer, error) {
+	if kind == RTPCodecTypeAudio {
+		return nil, nil
+	}
+
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(MimeTypeH264):
+		return &codecs.H264Packet{}, nil
+	case strings.ToLower(MimeTypeVP8):
+		return &codecs.VP8Packet{}, nil
+	case strings.ToLower(MimeTypeVP9):
+		return &codecs.VP9Packet{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", errFrameTransformUnsupportedCodec, codec.MimeType)
+	}
+}
+
+// frameTransformWriter sits between a TrackLocal and the interceptors of a
+// RTPSender. It assembles the packets written by the track into frames and
+// sends the transformed frames with their own sequence numbers.
+type frameTransformWriter struct {
+	mu sync.Mutex
+
+	transform    FrameTransform
+	depacketizer rtp.Depacketizer
+	codecErr     error
+	next         interceptor.RTPWriter
+
+	// header is the header of the first packet of the pending frame
+	header  rtp.Header
+	frame   []byte
+	pending bool
+
+	sequenceNumber uint16
+	started        bool
+}
+
+func newFrameTransformWriter(transform FrameTransform, kind RTPCodecType, codec RTPCodecCapability, next interceptor.RTPWriter) *frameTransformWriter {
+	depacketizer, err := depacketizerForFrameTransform(kind, codec)
+	return &frameTransformWriter{
+		transform:    transform,
+		depacketizer: depacketizer,
+		codecErr:     err,
+		next:         next,
+	}
+}
+
+func (w *frameTransformWriter) Write(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.codecErr != nil {
+		return 0, w.codecErr
+	}
+
+	if !w.started {
+		w.sequenceNumber = header.SequenceNumber
+		w.started = true
+	}
+
+	n := header.MarshalSize() + len(payload)
+	if w.depacketizer == nil {
+		return n, w.writeFrame(header.Clone(), payload, attributes)
+	}
+
+	// The previous frame ended without a marker, send what we have
+	if w.pending && header.Timestamp != w.header.Timestamp {
+		w.pending = false
+		w.header.Marker = true
+		if err := w.writeFrame(w.header, w.frame, attributes); err != nil {
+			return 0, err
+		}
+	}
+
+	data, err := w.depacketizer.Unmarshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	if !w.pending {
+		w.header = header.Clone()
+		w.frame = w.frame[:0]
+		w.pending = true
+	}
+	w.frame = append(w.frame, data...)
+
+	if !header.Marker {
+		return n, nil
+	}
+
+	w.pending = false
+	w.header.Marker = true
+	return n, w.writeFrame(w.header, w.frame, attributes)
+}
+
+// writeFrame transforms frame and sends it with the codec independent payload
+// format. Only the last packet keeps the marker of header.
+func (w *frameTransformWriter) writeFrame(header rtp.Header, frame []byte, attributes interceptor.Attributes) error {
+	transformed, err := w.transform(frame)
+	if err != nil {
+		return err
+	}
+
+	payloads := framePayloads(rtpOutboundMTU-header.MarshalSize(), transformed)
+	for i, payload := range payloads {
+		h := header.Clone()
+		h.SequenceNumber = w.sequenceNumber
+		h.Marker = header.Marker && i == len(payloads)-1
+		w.sequenceNumber++
+
+		if _, err := w.next.Write(&h, payload, attributes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// frameTransformReader assembles the transformed frames read from a
+// TrackRemote and packetizes them again for the codec of the track.
+type frameTransformReader struct {
+	mu sync.Mutex
+
+	builder *samplebuilder.SampleBuilder
+	buf     []byte
+
+	// headers holds the header of the first packet of every frame in builder
+	headers map[uint32]rtp.Header
+
+	payloader         rtp.Payloader
+	payloaderMimeType string
+	sequenceNumber    uint16
+	started           bool
+	packets           [][]byte
+}
+
+func newFrameTransformReader(clockRate uint32, receiveMTU uint) *frameTransformReader {
+	return &frameTransformReader{
+		builder: samplebuilder.New(frameTransformMaxLate, frameDepacketizer{}, clockRate),
+		buf:     make([]byte, receiveMTU),
+		headers: map[uint32]rtp.Header{},
+	}
+}
+
+func (fr *frameTransformReader) read(t *TrackRemote, b []byte, transform FrameTransform) (int, interceptor.Attributes, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+
+	for len(fr.packets) == 0 {
+		n, _, err := t.readRaw(fr.buf)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		packet := &rtp.Packet{}
+		if err := packet.Unmarshal(append([]byte{}, fr.buf[:n]...)); err != nil {
+			return 0, nil, err
+		}
+
+		if !fr.started {
+			fr.sequenceNumber = packet.SequenceNumber
+			fr.started = true
+		}
+		if _, ok := fr.headers[packet.Timestamp]; !ok || (frameDepacketizer{}).IsPartitionHead(packet.Payload) {
+			fr.headers[packet.Timestamp] = packet.Header.Clone()
+		}
+		fr.builder.Push(packet)
+
+		for sample := fr.builder.Pop(); sample != nil; sample = fr.builder.Pop() {
+			header := fr.popHeader(sample.PacketTimestamp)
+
+			// Frames the transform fails on are dropped like lost frames
+			frame, err := transform(sample.Data)
+			if err != nil {
+				continue
+			}
+
+			if err := fr.packetize(t.Kind(), t.Codec().RTPCodecCapability, header, frame); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+
+	packet := fr.packets[0]
+	fr.packets = fr.packets[1:]
+	if len(b) < len(packet) {
+		return 0, nil, io.ErrShortBuffer
+	}
+	return copy(b, packet), interceptor.Attributes{}, nil
+}
+
+// popHeader returns the header of the frame at timestamp and forgets the
+// headers of this and all older frames
+func (fr *frameTransformReader) popHeader(timestamp uint32) rtp.Header {
+	header := fr.headers[timestamp]
+	for ts := range fr.headers {
+		if int32(ts-timestamp) <= 0 {
+			delete(fr.headers, ts)
+		}
+	}
+	return header
+}
+
+func (fr *frameTransformReader) packetize(kind RTPCodecType, codec RTPCodecCapability, header rtp.Header, frame []byte) error {
+	payloads := [][]byte{frame}
+	if kind == RTPCodecTypeVideo {
+		if fr.payloader == nil || fr.payloaderMimeType != codec.MimeType {
+			payloader, err := payloaderForCodec(codec)
+			if err != nil {
+				return err
+			}
+			fr.payloader, fr.payloaderMimeType = payloader, codec.MimeType
+		}
+
+		payloads = fr.payloader.Payload(uint16(rtpOutboundMTU-header.MarshalSize()), frame)
+		header.Marker = true
+	}
+
+	for i, payload := range payloads {
+		h := header.Clone()
+		h.SequenceNumber = fr.sequenceNumber
+		h.Marker = header.Marker && i == len(payloads)-1
+		h.Padding = false
+		fr.sequenceNumber++
+
+		raw, err := (&rtp.Packet{Header: h, Payload: payload}).Marshal()
+		if err != nil {
+			return err
+		}
+		fr.packets = append(fr.packets, raw)
+	}
+	return nil
+}
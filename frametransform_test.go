@@ -0,0 +1,228 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+	"github.com/pion/webrtc/v3/pkg/media/sframe"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFramePayloads(t *testing.T) {
+	frame := []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x01, 0x02}
+
+	payloads := framePayloads(4, frame)
+	assert.Equal(t, [][]byte{
+		{0x80, 0x00, 0x00, 0x00},
+		{0x00, 0x01, 0x65, 0x01},
+		{0x40, 0x02},
+	}, payloads)
+
+	assembled := []byte{}
+	for i, payload := range payloads {
+		assert.Equal(t, i == 0, frameDepacketizer{}.IsPartitionHead(payload))
+		assert.Equal(t, i == len(payloads)-1, frameDepacketizer{}.IsPartitionTail(false, payload))
+
+		data, err := frameDepacketizer{}.Unmarshal(payload)
+		assert.NoError(t, err)
+		assembled = append(assembled, data...)
+	}
+	assert.Equal(t, frame, assembled)
+
+	assert.Equal(t, [][]byte{{0xc0}}, framePayloads(4, nil))
+
+	_, err := frameDepacketizer{}.Unmarshal(nil)
+	assert.Error(t, err)
+}
+
+// Assert that the packets written by a track are assembled into frames before
+// the transform and that the transformed frames don't go through the H264
+// payloader, which would split them on start codes
+func TestFrameTransformWriter(t *testing.T) {
+	transformed := []byte{0xff, 0x00, 0x00, 0x00, 0x01, 0x41, 0xff}
+
+	var frames [][]byte
+	var written []rtp.Packet
+	w := newFrameTransformWriter(func(frame []byte) ([]byte, error) {
+		frames = append(frames, append([]byte{}, frame...))
+		return transformed, nil
+	}, RTPCodecTypeVideo, RTPCodecCapability{MimeType: MimeTypeH264}, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, _ interceptor.Attributes) (int, error) {
+		written = append(written, rtp.Packet{Header: *header, Payload: append([]byte{}, payload...)})
+		return len(payload), nil
+	}))
+
+	frame := append([]byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x00, 0x00, 0x00, 0x01, 0x65}, bytes.Repeat([]byte{0x10}, 2000)...)
+	packetizer := rtp.NewPacketizer(rtpOutboundMTU, 96, 0x1234, &codecs.H264Payloader{}, rtp.NewFixedSequencer(100), 90000)
+	packets := packetizer.Packetize(frame, 3000)
+	assert.Greater(t, len(packets), 2)
+
+	for _, p := range packets {
+		_, err := w.Write(&p.Header, p.Payload, nil)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, [][]byte{frame}, frames)
+	if assert.Len(t, written, 1) {
+		assert.Equal(t, uint16(100), written[0].SequenceNumber)
+		assert.Equal(t, packets[0].Timestamp, written[0].Timestamp)
+		assert.True(t, written[0].Marker)
+		assert.Equal(t, append([]byte{0xc0}, transformed...), written[0].Payload)
+	}
+
+	// The next frame continues the sequence numbers of the transformed frames
+	for _, p := range packetizer.Packetize(frame, 3000) {
+		_, err := w.Write(&p.Header, p.Payload, nil)
+		assert.NoError(t, err)
+	}
+	if assert.Len(t, written, 2) {
+		assert.Equal(t, uint16(101), written[1].SequenceNumber)
+	}
+
+	w = newFrameTransformWriter(func(frame []byte) ([]byte, error) {
+		return frame, nil
+	}, RTPCodecTypeVideo, RTPCodecCapability{MimeType: MimeTypeAV1}, nil)
+	_, err := w.Write(&rtp.Header{}, []byte{0x00}, nil)
+	assert.True(t, errors.Is(err, errFrameTransformUnsupportedCodec))
+}
+
+func Test_RTPSender_SetFrameTransform_AfterSend(t *testing.T) {
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+
+	api := NewAPI()
+	dtlsTransport, err := api.NewDTLSTransport(nil, nil)
+	assert.NoError(t, err)
+
+	rtpSender, err := api.NewRTPSender(track, dtlsTransport)
+	assert.NoError(t, err)
+	assert.NoError(t, rtpSender.SetFrameTransform(func(frame []byte) ([]byte, error) { return frame, nil }))
+
+	close(rtpSender.sendCalled)
+	assert.Equal(t, errRTPSenderSendAlreadyCalled, rtpSender.SetFrameTransform(nil))
+}
+
+// Assert that SFrame encrypted frames are decrypted by the RTPReceiver and
+// read as packets of the codec of the track
+func Test_FrameTransform_SFrame(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	baseKey := []byte("0123456789abcdef")
+
+	for _, testCase := range []struct {
+		name            string
+		mimeType        string
+		newTrack        func() (TrackLocal, func(frame []byte) error, error)
+		newDepacketizer func() rtp.Depacketizer
+	}{
+		{
+			name:     "H264 with TrackLocalStaticSample",
+			mimeType: MimeTypeH264,
+			newTrack: func() (TrackLocal, func(frame []byte) error, error) {
+				track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeH264}, "video", "pion")
+				return track, func(frame []byte) error {
+					return track.WriteSample(media.Sample{Data: frame, Duration: time.Second / 30})
+				}, err
+			},
+			newDepacketizer: func() rtp.Depacketizer { return &codecs.H264Packet{} },
+		},
+		{
+			name:     "VP8 with TrackLocalStaticRTP",
+			mimeType: MimeTypeVP8,
+			newTrack: func() (TrackLocal, func(frame []byte) error, error) {
+				track, err := NewTrackLocalStaticRTP(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+				packetizer := rtp.NewPacketizer(rtpOutboundMTU, 0, 0, &codecs.VP8Payloader{}, rtp.NewRandomSequencer(), 90000)
+				return track, func(frame []byte) error {
+					for _, p := range packetizer.Packetize(frame, 3000) {
+						if err := track.WriteRTP(p); err != nil {
+							return err
+						}
+					}
+					return nil
+				}, err
+			},
+			newDepacketizer: func() rtp.Depacketizer { return &codecs.VP8Packet{} },
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			encrypter, err := sframe.NewContext(sframe.CipherSuiteAES128GCMSHA256)
+			assert.NoError(t, err)
+			assert.NoError(t, encrypter.AddKey(1, baseKey))
+			assert.NoError(t, encrypter.SetSendKey(1))
+
+			decrypter, err := sframe.NewContext(sframe.CipherSuiteAES128GCMSHA256)
+			assert.NoError(t, err)
+			assert.NoError(t, decrypter.AddKey(1, baseKey))
+
+			pcOffer, pcAnswer, err := newPair()
+			assert.NoError(t, err)
+
+			track, writeFrame, err := testCase.newTrack()
+			assert.NoError(t, err)
+
+			sender, err := pcOffer.AddTrack(track)
+			assert.NoError(t, err)
+			assert.NoError(t, sender.SetFrameTransform(encrypter.Encrypt))
+
+			frameFor := func(i byte) []byte {
+				payload := bytes.Repeat([]byte{0x10 + i%0x80}, 2500)
+				if testCase.mimeType == MimeTypeH264 {
+					return append([]byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x00, 0x00, 0x00, 0x01, 0x65}, payload...)
+				}
+				return append([]byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, payload...)
+			}
+
+			received, receivedFunc := context.WithCancel(context.Background())
+			pcAnswer.OnTrack(func(trackRemote *TrackRemote, r *RTPReceiver) {
+				r.SetFrameTransform(decrypter.Decrypt)
+
+				builder := samplebuilder.New(10, testCase.newDepacketizer(), trackRemote.Codec().ClockRate)
+				for {
+					pkt, _, readErr := trackRemote.ReadRTP()
+					if readErr != nil {
+						return
+					}
+
+					builder.Push(pkt)
+					for sample := builder.Pop(); sample != nil; sample = builder.Pop() {
+						// The first frames may have been dropped before the track fired
+						found := false
+						for i := 0; i < 256 && !found; i++ {
+							found = bytes.Equal(frameFor(byte(i)), sample.Data)
+						}
+						assert.True(t, found, "received frame was not sent")
+						receivedFunc()
+					}
+				}
+			})
+
+			assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+			for i := byte(0); ; i++ {
+				select {
+				case <-received.Done():
+					closePairNow(t, pcOffer, pcAnswer)
+					return
+				case <-time.After(20 * time.Millisecond):
+					assert.NoError(t, writeFrame(frameFor(i)))
+				}
+			}
+		})
+	}
+}
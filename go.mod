@@ -13,12 +13,12 @@ require (
 	github.com/pion/randutil v0.1.0
 	github.com/pion/rtcp v1.2.9
 	github.com/pion/rtp v1.7.13
-	github.com/pion/sctp v1.8.2
+	github.com/pion/sctp v1.8.13
 	github.com/pion/sdp/v3 v3.0.5
 	github.com/pion/srtp/v2 v2.0.9
 	github.com/pion/transport v0.13.0
+	github.com/pion/turn/v2 v2.0.8
 	github.com/sclevine/agouti v3.0.0+incompatible
-	github.com/stretchr/testify v1.7.1
-	golang.org/x/crypto v0.0.0-20220516162934-403b01795ae8 // indirect
-	golang.org/x/net v0.0.0-20220425223048-2871e0cb64e4
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/net v0.14.0
 )
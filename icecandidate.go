@@ -67,6 +67,33 @@ func newICECandidateFromICE(i ice.Candidate) (ICECandidate, error) {
 	return c, nil
 }
 
+func newICECandidatesFromStats(candidateStats []ice.CandidateStats) ([]ICECandidate, error) {
+	candidates := []ICECandidate{}
+
+	for _, stats := range candidateStats {
+		typ, err := convertTypeFromICE(stats.CandidateType)
+		if err != nil {
+			return nil, err
+		}
+		protocol, err := NewICEProtocol(stats.NetworkType.NetworkShort())
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, ICECandidate{
+			statsID:   stats.ID,
+			Priority:  stats.Priority,
+			Address:   stats.IP,
+			Protocol:  protocol,
+			Port:      uint16(stats.Port),
+			Component: ice.ComponentRTP,
+			Typ:       typ,
+		})
+	}
+
+	return candidates, nil
+}
+
 func (c ICECandidate) toICE() (ice.Candidate, error) {
 	candidateID := c.statsID
 	switch c.Typ {
@@ -7,6 +7,11 @@ type ICECandidatePair struct {
 	statsID string
 	Local   *ICECandidate
 	Remote  *ICECandidate
+
+	// State and Nominated describe the pair in the ICE checklist. They are
+	// only set by ICETransport.GetCandidatePairs.
+	State     StatsICECandidatePairState
+	Nominated bool
 }
 
 func newICECandidatePairStatsID(localID, remoteID string) string {
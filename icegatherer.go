@@ -24,6 +24,12 @@ type ICEGatherer struct {
 	validatedServers []*ice.URL
 	gatherPolicy     ICETransportPolicy
 
+	// pendingServers and pendingPolicy are set by setICEServers once the
+	// agent exists. They are used by the agent created by replaceAgent.
+	pendingServers    []*ice.URL
+	pendingPolicy     ICETransportPolicy
+	hasPendingServers bool
+
 	agent *ice.Agent
 
 	onLocalCandidateHandler atomic.Value // func(candidate *ICECandidate)
@@ -39,15 +45,11 @@ type ICEGatherer struct {
 // This constructor is part of the ORTC API. It is not
 // meant to be used together with the basic WebRTC API.
 func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
-	var validatedServers []*ice.URL
-	if len(opts.ICEServers) > 0 {
-		for _, server := range opts.ICEServers {
-			url, err := server.urls()
-			if err != nil {
-				return nil, err
-			}
-			validatedServers = append(validatedServers, url...)
-		}
+	log := api.settingEngine.LoggerFactory.NewLogger("ice")
+
+	validatedServers, err := validateICEServers(opts.ICEServers, log)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ICEGatherer{
@@ -55,10 +57,26 @@ func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
 		gatherPolicy:     opts.ICEGatherPolicy,
 		validatedServers: validatedServers,
 		api:              api,
-		log:              api.settingEngine.LoggerFactory.NewLogger("ice"),
+		log:              log,
 	}, nil
 }
 
+func validateICEServers(servers []ICEServer, log logging.LeveledLogger) ([]*ice.URL, error) {
+	var validatedServers []*ice.URL
+	for _, server := range servers {
+		url, err := server.urls()
+		if err != nil {
+			return nil, err
+		}
+		if server.CredentialType == ICECredentialTypeOauth {
+			log.Warnf("OAuth credentials are not supported by the ICE agent yet, no relay candidates will be gathered from %v", server.URLs)
+		}
+		validatedServers = append(validatedServers, url...)
+	}
+
+	return validatedServers, nil
+}
+
 func (g *ICEGatherer) createAgent() error {
 	g.lock.Lock()
 	defer g.lock.Unlock()
@@ -67,6 +85,18 @@ func (g *ICEGatherer) createAgent() error {
 		return nil
 	}
 
+	agent, err := g.newAgent()
+	if err != nil {
+		return err
+	}
+
+	g.agent = agent
+	return nil
+}
+
+// newAgent creates an ice.Agent for the current ICE servers and gather
+// policy. The caller must hold the lock.
+func (g *ICEGatherer) newAgent() (*ice.Agent, error) {
 	candidateTypes := []ice.CandidateType{}
 	if g.api.settingEngine.candidates.ICELite {
 		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
@@ -92,7 +122,7 @@ func (g *ICEGatherer) createAgent() error {
 
 	config := &ice.AgentConfig{
 		Lite:                   g.api.settingEngine.candidates.ICELite,
-		Urls:                   g.validatedServers,
+		Urls:                   copyICEURLs(g.validatedServers),
 		PortMin:                g.api.settingEngine.ephemeralUDP.PortMin,
 		PortMax:                g.api.settingEngine.ephemeralUDP.PortMax,
 		DisconnectedTimeout:    g.api.settingEngine.timeout.ICEDisconnectedTimeout,
@@ -126,13 +156,7 @@ func (g *ICEGatherer) createAgent() error {
 		config.NetworkTypes = append(config.NetworkTypes, ice.NetworkType(typ))
 	}
 
-	agent, err := ice.NewAgent(config)
-	if err != nil {
-		return err
-	}
-
-	g.agent = agent
-	return nil
+	return ice.NewAgent(config)
 }
 
 // Gather ICE candidates.
@@ -168,7 +192,10 @@ func (g *ICEGatherer) Gather() error {
 				g.log.Warnf("Failed to convert ice.Candidate: %s", err)
 				return
 			}
-			onLocalCandidateHandler(&c)
+
+			if c, ok := g.filterCandidate(c); ok {
+				onLocalCandidateHandler(&c)
+			}
 		} else {
 			g.setState(ICEGathererStateComplete)
 
@@ -221,12 +248,39 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 	if err := g.createAgent(); err != nil {
 		return nil, err
 	}
-	iceCandidates, err := g.agent.GetLocalCandidates()
+
+	return g.localCandidates(g.agent)
+}
+
+// localCandidates returns the candidates gathered by agent as changed by the
+// filter set by SettingEngine.SetCandidateFilter
+func (g *ICEGatherer) localCandidates(agent *ice.Agent) ([]ICECandidate, error) {
+	iceCandidates, err := agent.GetLocalCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := newICECandidatesFromICE(iceCandidates)
 	if err != nil {
 		return nil, err
 	}
 
-	return newICECandidatesFromICE(iceCandidates)
+	filtered := candidates[:0]
+	for _, c := range candidates {
+		if c, ok := g.filterCandidate(c); ok {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// filterCandidate applies the filter set by SettingEngine.SetCandidateFilter
+func (g *ICEGatherer) filterCandidate(c ICECandidate) (ICECandidate, bool) {
+	if g.api.settingEngine.candidates.CandidateFilter == nil {
+		return c, true
+	}
+
+	return g.api.settingEngine.candidates.CandidateFilter(c)
 }
 
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
@@ -253,6 +307,89 @@ func (g *ICEGatherer) setState(s ICEGathererState) {
 	}
 }
 
+// setICEServers updates the ICE servers and gather policy of the ICEGatherer.
+// Before the ICE agent is created they are simply replaced. The agent can't
+// change its servers, so afterwards they are held back until replaceAgent
+// creates a new agent on the next ICE restart.
+func (g *ICEGatherer) setICEServers(servers []ICEServer, policy ICETransportPolicy) error {
+	urls, err := validateICEServers(servers, g.log)
+	if err != nil {
+		return err
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.agent == nil {
+		g.validatedServers = urls
+		g.gatherPolicy = policy
+		return nil
+	}
+
+	changed := policy != g.gatherPolicy || len(urls) != len(g.validatedServers)
+	for i := 0; !changed && i < len(urls); i++ {
+		changed = !sameICEURL(urls[i], g.validatedServers[i]) ||
+			urls[i].Username != g.validatedServers[i].Username ||
+			urls[i].Password != g.validatedServers[i].Password
+	}
+
+	g.pendingServers = urls
+	g.pendingPolicy = policy
+	g.hasPendingServers = changed
+	return nil
+}
+
+// havePendingServers returns true if setICEServers changed the servers or
+// policy since the agent was created
+func (g *ICEGatherer) havePendingServers() bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	return g.hasPendingServers
+}
+
+// replaceAgent creates a new agent with the pending ICE servers and gather
+// policy and returns the agent it replaces. The caller is responsible for
+// closing the previous agent and for calling Gather on the new one.
+func (g *ICEGatherer) replaceAgent() (*ice.Agent, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.agent == nil {
+		return nil, fmt.Errorf("%w: unable to replace agent", errICEAgentNotExist)
+	}
+
+	validatedServers, gatherPolicy := g.validatedServers, g.gatherPolicy
+	if g.hasPendingServers {
+		g.validatedServers, g.gatherPolicy = g.pendingServers, g.pendingPolicy
+	}
+
+	agent, err := g.newAgent()
+	if err != nil {
+		g.validatedServers, g.gatherPolicy = validatedServers, gatherPolicy
+		return nil, err
+	}
+
+	previous := g.agent
+	g.agent = agent
+	g.pendingServers = nil
+	g.hasPendingServers = false
+	return previous, nil
+}
+
+func sameICEURL(a, b *ice.URL) bool {
+	return a.Scheme == b.Scheme && a.Host == b.Host && a.Port == b.Port && a.Proto == b.Proto
+}
+
+func copyICEURLs(urls []*ice.URL) []*ice.URL {
+	copies := make([]*ice.URL, 0, len(urls))
+	for _, url := range urls {
+		c := *url
+		copies = append(copies, &c)
+	}
+	return copies
+}
+
 func (g *ICEGatherer) getAgent() *ice.Agent {
 	g.lock.RLock()
 	defer g.lock.RUnlock()
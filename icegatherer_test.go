@@ -98,3 +98,98 @@ func TestICEGather_mDNSCandidateGathering(t *testing.T) {
 	<-gotMulticastDNSCandidate.Done()
 	assert.NoError(t, gatherer.Close())
 }
+
+func TestICEGatherer_SetICEServers(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	turnServer := func(url, password string) []ICEServer {
+		return []ICEServer{{URLs: []string{url}, Username: "user", Credential: password}}
+	}
+
+	gatherer, err := NewAPI().NewICEGatherer(ICEGatherOptions{
+		ICEServers: turnServer("turn:127.0.0.1:3478", "initial"),
+	})
+	assert.NoError(t, err)
+
+	// Before the agent exists everything can be replaced
+	assert.NoError(t, gatherer.setICEServers(turnServer("turn:127.0.0.2:3478", "initial"), ICETransportPolicyRelay))
+	assert.Equal(t, "127.0.0.2", gatherer.validatedServers[0].Host)
+	assert.Equal(t, ICETransportPolicyRelay, gatherer.gatherPolicy)
+
+	_, err = gatherer.GetLocalParameters()
+	assert.NoError(t, err)
+
+	// Once the agent exists changes are held back for the next agent
+	agent := gatherer.getAgent()
+	assert.NoError(t, gatherer.setICEServers(turnServer("turn:127.0.0.2:3478", "initial"), ICETransportPolicyRelay))
+	assert.False(t, gatherer.havePendingServers())
+
+	assert.NoError(t, gatherer.setICEServers(turnServer("turn:127.0.0.1:3478", "rotated"), ICETransportPolicyAll))
+	assert.True(t, gatherer.havePendingServers())
+	assert.Equal(t, "127.0.0.2", gatherer.validatedServers[0].Host)
+	assert.Equal(t, ICETransportPolicyRelay, gatherer.gatherPolicy)
+
+	previous, err := gatherer.replaceAgent()
+	assert.NoError(t, err)
+	assert.Equal(t, agent, previous)
+	assert.NotEqual(t, agent, gatherer.getAgent())
+	assert.False(t, gatherer.havePendingServers())
+	assert.Equal(t, "127.0.0.1", gatherer.validatedServers[0].Host)
+	assert.Equal(t, "rotated", gatherer.validatedServers[0].Password)
+	assert.Equal(t, ICETransportPolicyAll, gatherer.gatherPolicy)
+	assert.NoError(t, previous.Close())
+
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGatherer_CandidateFilter(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+	s.SetCandidateFilter(func(c ICECandidate) (ICECandidate, bool) {
+		if c.Address == "127.0.0.1" {
+			return c, false
+		}
+
+		c.Address = "10.0.0.1"
+		c.Port = 1234
+		return c, true
+	})
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gatherFinished)
+			return
+		}
+
+		assert.Equal(t, "10.0.0.1", c.Address)
+		assert.Equal(t, uint16(1234), c.Port)
+	})
+
+	assert.NoError(t, gatherer.Gather())
+	<-gatherFinished
+
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	for _, c := range candidates {
+		assert.Equal(t, "10.0.0.1", c.Address)
+		assert.Equal(t, uint16(1234), c.Port)
+	}
+
+	// The ICETransport reports the same candidates
+	transportCandidates, err := NewAPI(WithSettingEngine(s)).NewICETransport(gatherer).GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.Equal(t, candidates, transportCandidates)
+
+	assert.NoError(t, gatherer.Close())
+}
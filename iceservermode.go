@@ -0,0 +1,137 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"strings"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/logging"
+	"github.com/pion/webrtc/v3/internal/util"
+)
+
+// iceServerModeTCPReadBufferSize is the number of packets buffered per ICE-TCP connection
+const iceServerModeTCPReadBufferSize = 8
+
+// ICEServerModeConfig configures NewICEServerMode
+type ICEServerModeConfig struct {
+	// UDPAddress and TCPAddress are the local addresses to listen on, e.g. ":8443".
+	// Leaving one empty disables that protocol, but at least one is required.
+	UDPAddress string
+	TCPAddress string
+
+	// PublicIPs are advertised in the host candidates instead of the local
+	// interface addresses. They use the format of SettingEngine.SetNAT1To1IPs,
+	// so "public/private" pairs map each interface to its own public IP.
+	PublicIPs []string
+
+	LoggerFactory logging.LoggerFactory
+}
+
+// ICEServerMode shares one UDP and one TCP port between all PeerConnections
+// of the SettingEngines it is set on. Incoming traffic is demultiplexed by the
+// ICE username fragment. Use it with SettingEngine.SetICEServerMode.
+type ICEServerMode struct {
+	udpConn     net.PacketConn
+	tcpListener net.Listener
+	udpMux      ice.UDPMux
+	tcpMux      ice.TCPMux
+	publicIPs   []string
+}
+
+// NewICEServerMode binds the UDP and TCP ports described by config
+func NewICEServerMode(config ICEServerModeConfig) (*ICEServerMode, error) {
+	if config.UDPAddress == "" && config.TCPAddress == "" {
+		return nil, errICEServerModeNoAddress
+	}
+
+	loggerFactory := config.LoggerFactory
+	if loggerFactory == nil {
+		loggerFactory = logging.NewDefaultLoggerFactory()
+	}
+	log := loggerFactory.NewLogger("ice")
+
+	s := &ICEServerMode{publicIPs: config.PublicIPs}
+
+	if config.UDPAddress != "" {
+		udpConn, err := net.ListenPacket("udp", config.UDPAddress)
+		if err != nil {
+			return nil, err
+		}
+		s.udpConn = udpConn
+		s.udpMux = NewICEUDPMux(log, udpConn)
+	}
+
+	if config.TCPAddress != "" {
+		tcpListener, err := net.Listen("tcp", config.TCPAddress)
+		if err != nil {
+			return nil, util.FlattenErrs([]error{err, s.Close()})
+		}
+		s.tcpListener = tcpListener
+		s.tcpMux = NewICETCPMux(log, tcpListener, iceServerModeTCPReadBufferSize)
+	}
+
+	return s, nil
+}
+
+// UDPAddr returns the local address of the shared UDP port, or nil if UDP is disabled
+func (s *ICEServerMode) UDPAddr() net.Addr {
+	if s.udpConn == nil {
+		return nil
+	}
+	return s.udpConn.LocalAddr()
+}
+
+// TCPAddr returns the local address of the shared TCP port, or nil if TCP is disabled
+func (s *ICEServerMode) TCPAddr() net.Addr {
+	if s.tcpListener == nil {
+		return nil
+	}
+	return s.tcpListener.Addr()
+}
+
+// Close releases the shared ports. PeerConnections using them stop working.
+func (s *ICEServerMode) Close() error {
+	var closeErrs []error
+	if s.udpMux != nil {
+		closeErrs = append(closeErrs, s.udpMux.Close(), s.udpConn.Close())
+	}
+	if s.tcpMux != nil {
+		// Closing the TCPMux closes its listener
+		closeErrs = append(closeErrs, s.tcpMux.Close())
+	}
+
+	return util.FlattenErrs(closeErrs)
+}
+
+// networkTypes returns the network types to gather host candidates for.
+// With PublicIPs only the address families that can be mapped are used.
+func (s *ICEServerMode) networkTypes() (networkTypes []NetworkType) {
+	ipv4, ipv6 := len(s.publicIPs) == 0, len(s.publicIPs) == 0
+	for _, mapping := range s.publicIPs {
+		publicIP := net.ParseIP(strings.Split(mapping, "/")[0])
+		switch {
+		case publicIP == nil:
+		case publicIP.To4() != nil:
+			ipv4 = true
+		default:
+			ipv6 = true
+		}
+	}
+
+	if s.udpMux != nil && ipv4 {
+		networkTypes = append(networkTypes, NetworkTypeUDP4)
+	}
+	if s.udpMux != nil && ipv6 {
+		networkTypes = append(networkTypes, NetworkTypeUDP6)
+	}
+	if s.tcpMux != nil && ipv4 {
+		networkTypes = append(networkTypes, NetworkTypeTCP4)
+	}
+	if s.tcpMux != nil && ipv6 {
+		networkTypes = append(networkTypes, NetworkTypeTCP6)
+	}
+	return networkTypes
+}
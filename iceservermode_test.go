@@ -0,0 +1,58 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICEServerMode(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	_, err := NewICEServerMode(ICEServerModeConfig{})
+	assert.ErrorIs(t, err, errICEServerModeNoAddress)
+
+	serverMode, err := NewICEServerMode(ICEServerModeConfig{
+		UDPAddress: ":0",
+		TCPAddress: ":0",
+		PublicIPs:  []string{"127.0.0.1"},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, serverMode.TCPAddr())
+	udpPort := serverMode.UDPAddr().(*net.UDPAddr).Port
+
+	s := SettingEngine{}
+	s.SetICEServerMode(serverMode)
+	serverAPI := NewAPI(WithSettingEngine(s))
+
+	// Connect multiple PeerConnections through the same port
+	for i := 0; i < 2; i++ {
+		client, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		server, err := serverAPI.NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		connected := untilConnectionState(PeerConnectionStateConnected, client, server)
+		assert.NoError(t, signalPair(client, server))
+		connected.Wait()
+
+		pair, err := server.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+		assert.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", pair.Local.Address)
+		assert.Equal(t, uint16(udpPort), pair.Local.Port)
+
+		closePairNow(t, client, server)
+	}
+
+	assert.NoError(t, serverMode.Close())
+}
@@ -5,7 +5,9 @@ package webrtc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,6 +15,7 @@ import (
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
 	"github.com/pion/webrtc/v3/internal/mux"
+	"github.com/pion/webrtc/v3/internal/util"
 )
 
 // ICETransport allows an application access to information about the ICE
@@ -28,9 +31,16 @@ type ICETransport struct {
 
 	state atomic.Value // ICETransportState
 
-	gatherer *ICEGatherer
-	conn     *ice.Conn
-	mux      *mux.Mux
+	gatherer  *ICEGatherer
+	conn      *ice.Conn
+	connAgent *ice.Agent
+	muxConn   *replaceableConn
+	mux       *mux.Mux
+
+	// pendingAgent was created by an ICE restart with changed ICE servers
+	// and is connected by connectAgent once the remote credentials are known
+	pendingAgent    *ice.Agent
+	agentConnecting sync.WaitGroup
 
 	ctx       context.Context
 	ctxCancel func()
@@ -66,6 +76,76 @@ func (t *ICETransport) GetSelectedCandidatePair() (*ICECandidatePair, error) {
 	return &ICECandidatePair{Local: &local, Remote: &remote}, nil
 }
 
+// GetLocalCandidates returns the local candidates of the ICETransport as
+// changed by the filter set by SettingEngine.SetCandidateFilter
+func (t *ICETransport) GetLocalCandidates() ([]ICECandidate, error) {
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return nil, nil
+	}
+
+	return t.gatherer.localCandidates(agent)
+}
+
+// GetRemoteCandidates returns the remote candidates of the ICETransport,
+// including the peer reflexive candidates discovered by connectivity checks.
+// Foundation and the related address are not known for remote candidates.
+func (t *ICETransport) GetRemoteCandidates() ([]ICECandidate, error) {
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return nil, nil
+	}
+
+	return newICECandidatesFromStats(agent.GetRemoteCandidatesStats())
+}
+
+// GetCandidatePairs returns all candidate pairs of the ICE checklist along
+// with their state
+func (t *ICETransport) GetCandidatePairs() ([]*ICECandidatePair, error) {
+	agent := t.gatherer.getAgent()
+	if agent == nil {
+		return nil, nil
+	}
+
+	localCandidates, err := t.GetLocalCandidates()
+	if err != nil {
+		return nil, err
+	}
+	remoteCandidates, err := t.GetRemoteCandidates()
+	if err != nil {
+		return nil, err
+	}
+
+	candidatesByID := map[string]*ICECandidate{}
+	for i := range localCandidates {
+		candidatesByID[localCandidates[i].statsID] = &localCandidates[i]
+	}
+	for i := range remoteCandidates {
+		candidatesByID[remoteCandidates[i].statsID] = &remoteCandidates[i]
+	}
+
+	pairs := []*ICECandidatePair{}
+	for _, stats := range agent.GetCandidatePairsStats() {
+		local, remote := candidatesByID[stats.LocalCandidateID], candidatesByID[stats.RemoteCandidateID]
+		// The checklist may have changed since the candidates were fetched
+		if local == nil || remote == nil {
+			continue
+		}
+
+		state, err := toStatsICECandidatePairState(stats.State)
+		if err != nil {
+			return nil, err
+		}
+
+		pair := NewICECandidatePair(local, remote)
+		pair.State = state
+		pair.Nominated = stats.Nominated
+		pairs = append(pairs, pair)
+	}
+
+	return pairs, nil
+}
+
 // NewICETransport creates a new NewICETransport.
 func NewICETransport(gatherer *ICEGatherer, loggerFactory logging.LoggerFactory) *ICETransport {
 	iceTransport := &ICETransport{
@@ -99,22 +179,7 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 		return fmt.Errorf("%w: unable to start ICETransport", errICEAgentNotExist)
 	}
 
-	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
-		state := newICETransportStateFromICE(iceState)
-
-		t.setState(state)
-		t.onConnectionStateChange(state)
-	}); err != nil {
-		return err
-	}
-	if err := agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
-		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
-		if err != nil {
-			t.log.Warnf("%w: %s", errICECandiatesCoversionFailed, err)
-			return
-		}
-		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
-	}); err != nil {
+	if err := t.setAgentHandlers(agent); err != nil {
 		return err
 	}
 
@@ -154,9 +219,11 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	}
 
 	t.conn = iceConn
+	t.connAgent = agent
+	t.muxConn = &replaceableConn{conn: iceConn}
 
 	config := mux.Config{
-		Conn:          t.conn,
+		Conn:          t.muxConn,
 		BufferSize:    int(t.gatherer.api.settingEngine.getReceiveMTU()),
 		LoggerFactory: t.loggerFactory,
 	}
@@ -165,12 +232,39 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	return nil
 }
 
-// restart is not exposed currently because ORTC has users create a whole new ICETransport
-// so for now lets keep it private so we don't cause ORTC users to depend on non-standard APIs
-func (t *ICETransport) restart() error {
+func (t *ICETransport) setAgentHandlers(agent *ice.Agent) error {
+	if err := agent.OnConnectionStateChange(func(iceState ice.ConnectionState) {
+		state := newICETransportStateFromICE(iceState)
+
+		t.setState(state)
+		t.onConnectionStateChange(state)
+	}); err != nil {
+		return err
+	}
+
+	return agent.OnSelectedCandidatePairChange(func(local, remote ice.Candidate) {
+		candidates, err := newICECandidatesFromICE([]ice.Candidate{local, remote})
+		if err != nil {
+			t.log.Warnf("%w: %s", errICECandiatesCoversionFailed, err)
+			return
+		}
+		t.onSelectedCandidatePairChange(NewICECandidatePair(&candidates[0], &candidates[1]))
+	})
+}
+
+// Restart restarts ICE with new local credentials and gathers candidates
+// again. The underlying connection is kept, so a DTLSTransport running on
+// top of the ICETransport is not torn down. The new local parameters have to
+// be signaled and passed to SetRemoteParameters on the remote side.
+// This is not part of the ORTC API, which creates a new ICETransport instead.
+func (t *ICETransport) Restart() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if t.gatherer.havePendingServers() {
+		return t.restartWithNewAgent()
+	}
+
 	agent := t.gatherer.getAgent()
 	if agent == nil {
 		return fmt.Errorf("%w: unable to restart ICETransport", errICEAgentNotExist)
@@ -182,23 +276,113 @@ func (t *ICETransport) restart() error {
 	return t.gatherer.Gather()
 }
 
+// restartWithNewAgent restarts ICE with a new agent using the ICE servers
+// and policy changed by SetConfiguration, a running ice.Agent can't change
+// them. The previous agent keeps carrying packets until the new one is
+// connected. The caller must hold the lock.
+func (t *ICETransport) restartWithNewAgent() error {
+	previous, err := t.gatherer.replaceAgent()
+	if err != nil {
+		return err
+	}
+
+	// Before Start, or if the previous agent never connected, it isn't
+	// carrying any packets
+	if t.mux == nil || previous != t.connAgent {
+		if err = previous.Close(); err != nil && !errors.Is(err, ice.ErrClosed) {
+			return err
+		}
+		t.pendingAgent = nil
+	} else {
+		// Events of the previous agent no longer describe the ICETransport
+		if err = previous.OnConnectionStateChange(func(ice.ConnectionState) {}); err != nil {
+			return err
+		}
+		if err = previous.OnSelectedCandidatePairChange(func(ice.Candidate, ice.Candidate) {}); err != nil {
+			return err
+		}
+	}
+
+	if t.mux != nil {
+		agent := t.gatherer.getAgent()
+		if err = t.setAgentHandlers(agent); err != nil {
+			return err
+		}
+		t.pendingAgent = agent
+	}
+	return t.gatherer.Gather()
+}
+
+// connectAgent runs the connectivity checks of an agent created by
+// restartWithNewAgent and moves the Mux over to it once it is connected
+func (t *ICETransport) connectAgent(ctx context.Context, agent *ice.Agent, role ICERole, ufrag, pwd string) {
+	defer t.agentConnecting.Done()
+
+	var conn *ice.Conn
+	var err error
+	if role == ICERoleControlling {
+		conn, err = agent.Dial(ctx, ufrag, pwd)
+	} else {
+		conn, err = agent.Accept(ctx, ufrag, pwd)
+	}
+	if err != nil {
+		if closeErr := agent.Close(); closeErr != nil && !errors.Is(closeErr, ice.ErrClosed) {
+			t.log.Warnf("Failed to close ICE agent: %s", closeErr)
+		}
+		if ctx.Err() == nil && !errors.Is(err, ice.ErrClosed) {
+			t.log.Warnf("Failed to connect ICE agent after restart: %s", err)
+		}
+		return
+	}
+
+	t.lock.Lock()
+	// Replaced again, or stopped while connecting
+	if ctx.Err() != nil || t.gatherer.getAgent() != agent {
+		t.lock.Unlock()
+		if err = conn.Close(); err != nil && !errors.Is(err, ice.ErrClosed) {
+			t.log.Warnf("Failed to close ICE agent: %s", err)
+		}
+		return
+	}
+
+	previous := t.conn
+	t.conn, t.connAgent = conn, agent
+	t.muxConn.replace(conn)
+	t.lock.Unlock()
+
+	if err = previous.Close(); err != nil && !errors.Is(err, ice.ErrClosed) {
+		t.log.Warnf("Failed to close previous ICE agent: %s", err)
+	}
+}
+
 // Stop irreversibly stops the ICETransport.
 func (t *ICETransport) Stop() error {
 	t.lock.Lock()
-	defer t.lock.Unlock()
-
 	t.setState(ICETransportStateClosed)
 
 	if t.ctxCancel != nil {
 		t.ctxCancel()
 	}
+	t.lock.Unlock()
+
+	// An agent connecting after an ICE restart gives up once ctx is canceled
+	t.agentConnecting.Wait()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	closeErrs := []error{}
+	if t.pendingAgent != nil {
+		closeErrs = append(closeErrs, t.pendingAgent.Close())
+		t.pendingAgent = nil
+	}
 
 	if t.mux != nil {
-		return t.mux.Close()
+		closeErrs = append(closeErrs, t.mux.Close())
 	} else if t.gatherer != nil {
-		return t.gatherer.Close()
+		closeErrs = append(closeErrs, t.gatherer.Close())
 	}
-	return nil
+	return util.FlattenErrs(closeErrs)
 }
 
 // OnSelectedCandidatePairChange sets a handler that is invoked when a new
@@ -322,7 +506,7 @@ func (t *ICETransport) ensureGatherer() error {
 	return nil
 }
 
-func (t *ICETransport) collectStats(collector *statsReportCollector) {
+func (t *ICETransport) collectStats(collector *statsReportCollector, dtlsTransport *DTLSTransport) {
 	t.lock.Lock()
 	conn := t.conn
 	t.lock.Unlock()
@@ -340,6 +524,10 @@ func (t *ICETransport) collectStats(collector *statsReportCollector) {
 		stats.BytesReceived = conn.BytesReceived()
 	}
 
+	if dtlsTransport != nil {
+		dtlsTransport.populateTransportStats(&stats)
+	}
+
 	collector.Collect(stats.ID, stats)
 }
 
@@ -360,10 +548,23 @@ func (t *ICETransport) haveRemoteCredentialsChange(newUfrag, newPwd string) bool
 	return uFrag != newUfrag || uPwd != newPwd
 }
 
+// SetRemoteParameters updates the remote ICE parameters, e.g. after the
+// remote side restarted ICE.
+func (t *ICETransport) SetRemoteParameters(params ICEParameters) error {
+	return t.setRemoteCredentials(params.UsernameFragment, params.Password)
+}
+
 func (t *ICETransport) setRemoteCredentials(newUfrag, newPwd string) error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if agent := t.pendingAgent; agent != nil {
+		t.pendingAgent = nil
+		t.agentConnecting.Add(1)
+		go t.connectAgent(t.ctx, agent, t.role, newUfrag, newPwd)
+		return nil
+	}
+
 	agent := t.gatherer.getAgent()
 	if agent == nil {
 		return fmt.Errorf("%w: unable to SetRemoteCredentials", errICEAgentNotExist)
@@ -371,3 +572,63 @@ func (t *ICETransport) setRemoteCredentials(newUfrag, newPwd string) error {
 
 	return agent.SetRemoteCredentials(newUfrag, newPwd)
 }
+
+// replaceableConn is the net.Conn the Mux reads from. It lets an ICE restart
+// move the Mux to the ice.Conn of a new ice.Agent without closing the
+// endpoints of the DTLSTransport.
+type replaceableConn struct {
+	lock sync.RWMutex
+	conn net.Conn
+}
+
+func (c *replaceableConn) current() net.Conn {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.conn
+}
+
+func (c *replaceableConn) replace(conn net.Conn) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.conn = conn
+}
+
+func (c *replaceableConn) Read(b []byte) (int, error) {
+	for {
+		conn := c.current()
+		n, err := conn.Read(b)
+		// The previous conn is closed after it has been replaced
+		if err != nil && c.current() != conn {
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *replaceableConn) Write(b []byte) (int, error) {
+	return c.current().Write(b)
+}
+
+func (c *replaceableConn) Close() error {
+	return c.current().Close()
+}
+
+func (c *replaceableConn) LocalAddr() net.Addr {
+	return c.current().LocalAddr()
+}
+
+func (c *replaceableConn) RemoteAddr() net.Addr {
+	return c.current().RemoteAddr()
+}
+
+func (c *replaceableConn) SetDeadline(t time.Time) error {
+	return c.current().SetDeadline(t)
+}
+
+func (c *replaceableConn) SetReadDeadline(t time.Time) error {
+	return c.current().SetReadDeadline(t)
+}
+
+func (c *replaceableConn) SetWriteDeadline(t time.Time) error {
+	return c.current().SetWriteDeadline(t)
+}
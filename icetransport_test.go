@@ -113,3 +113,52 @@ func TestICETransport_GetSelectedCandidatePair(t *testing.T) {
 
 	closePairNow(t, offerer, answerer)
 }
+
+func TestICETransport_GetCandidatePairs(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	iceTransport := pcOffer.SCTP().Transport().ICETransport()
+
+	// Nothing is known before the agent exists
+	pairs, err := iceTransport.GetCandidatePairs()
+	assert.NoError(t, err)
+	assert.Empty(t, pairs)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	localCandidates, err := iceTransport.GetLocalCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, localCandidates)
+
+	remoteCandidates, err := iceTransport.GetRemoteCandidates()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, remoteCandidates)
+
+	pairs, err = iceTransport.GetCandidatePairs()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pairs)
+
+	selectedPair, err := iceTransport.GetSelectedCandidatePair()
+	assert.NoError(t, err)
+
+	foundSelectedPair := false
+	for _, pair := range pairs {
+		if pair.Local.Address == selectedPair.Local.Address && pair.Local.Port == selectedPair.Local.Port &&
+			pair.Remote.Address == selectedPair.Remote.Address && pair.Remote.Port == selectedPair.Remote.Port {
+			foundSelectedPair = true
+			assert.Equal(t, StatsICECandidatePairStateSucceeded, pair.State)
+		}
+	}
+	assert.True(t, foundSelectedPair)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -224,6 +224,9 @@ func (m *MediaEngine) RegisterCodec(codec RTPCodecParameters, typ RTPCodecType)
 
 // RegisterHeaderExtension adds a header extension to the MediaEngine
 // To determine the negotiated value use `GetHeaderExtensionID` after signaling is complete
+// Header extensions are always sent unencrypted. Encrypted header extensions (RFC 6904,
+// urn:ietf:params:rtp-hdrext:encrypt) are not implemented, so values like the audio level
+// can be read on the wire.
 func (m *MediaEngine) RegisterHeaderExtension(extension RTPHeaderExtensionCapability, typ RTPCodecType, allowedDirections ...RTPTransceiverDirection) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -4,6 +4,11 @@ package webrtc
 // the STUN/TURN client to connect to an ICE server as defined in
 // https://tools.ietf.org/html/rfc7635. Note that the kid parameter is not
 // located in OAuthCredential, but in ICEServer's username member.
+//
+// OAuthCredential is validated and can be round-tripped through JSON, but
+// pion/ice can't yet send the ACCESS-TOKEN attribute or use the MAC key for
+// message integrity. TURN servers configured with it are skipped during
+// gathering.
 type OAuthCredential struct {
 	// MACKey is a base64-url encoded format. It is used in STUN message
 	// integrity hash calculation.
@@ -5,6 +5,7 @@ package webrtc
 
 import (
 	"io"
+	"net"
 	"testing"
 	"time"
 
@@ -63,3 +64,76 @@ func TestDataChannel_ORTCE2E(t *testing.T) {
 	assert.ErrorIs(t, channelA.SendText("test"), io.ErrClosedPipe)
 	assert.ErrorIs(t, channelA.ensureOpen(), io.ErrClosedPipe)
 }
+
+func TestDataChannel_ORTCWithConn(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcA, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	connB, err := net.DialUDP("udp4", nil, pcA.LocalAddr().(*net.UDPAddr))
+	assert.NoError(t, err)
+
+	api := NewAPI()
+
+	dtlsA, err := api.NewDTLSTransportWithPacketConn(pcA, connB.LocalAddr(), nil)
+	assert.NoError(t, err)
+
+	dtlsB, err := api.NewDTLSTransportWithConn(connB, nil)
+	assert.NoError(t, err)
+
+	paramsA, err := dtlsA.GetLocalParameters()
+	assert.NoError(t, err)
+
+	paramsB, err := dtlsB.GetLocalParameters()
+	assert.NoError(t, err)
+
+	// Without ICE the DTLS role must be explicit
+	assert.ErrorIs(t, dtlsA.Start(paramsB), errDTLSTransportRoleAuto)
+
+	paramsA.Role = DTLSRoleClient
+	paramsB.Role = DTLSRoleServer
+
+	startErr := make(chan error)
+	go func() {
+		startErr <- dtlsA.Start(paramsB)
+	}()
+	assert.NoError(t, dtlsB.Start(paramsA))
+	assert.NoError(t, <-startErr)
+
+	sctpA := api.NewSCTPTransport(dtlsA)
+	sctpB := api.NewSCTPTransport(dtlsB)
+
+	awaitMessage := make(chan struct{})
+	sctpB.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, "ABC", string(msg.Data))
+			close(awaitMessage)
+		})
+	})
+
+	go func() {
+		startErr <- sctpA.Start(sctpB.GetCapabilities())
+	}()
+	assert.NoError(t, sctpB.Start(sctpA.GetCapabilities()))
+	assert.NoError(t, <-startErr)
+
+	var id uint16 = 1
+	channelA, err := api.NewDataChannel(sctpA, &DataChannelParameters{
+		Label: "Foo",
+		ID:    &id,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, channelA.SendText("ABC"))
+
+	<-awaitMessage
+
+	assert.NoError(t, sctpA.Stop())
+	assert.NoError(t, sctpB.Stop())
+	assert.NoError(t, dtlsA.Stop())
+	assert.NoError(t, dtlsB.Stop())
+}
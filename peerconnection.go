@@ -53,8 +53,16 @@ type PeerConnection struct {
 
 	isClosed               *atomicBool
 	isNegotiationNeeded    *atomicBool
+	iceRestartRequested    *atomicBool
 	negotiationNeededState negotiationNeededState
 
+	// sdesLocalCryptos are set if SRTP is keyed with SDES instead of DTLS
+	sdesLocalCryptos []sdesCrypto
+	sdesRemoteCrypto atomic.Value // sdesCrypto
+
+	iceAutoRestartLock  sync.Mutex
+	iceAutoRestartTimer *time.Timer
+
 	lastOffer  string
 	lastAnswer string
 
@@ -124,6 +132,7 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		ops:                    newOperations(),
 		isClosed:               &atomicBool{},
 		isNegotiationNeeded:    &atomicBool{},
+		iceRestartRequested:    &atomicBool{},
 		negotiationNeededState: negotiationNeededStateEmpty,
 		lastOffer:              "",
 		lastAnswer:             "",
@@ -156,6 +165,12 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		return nil, err
 	}
 
+	if api.settingEngine.sdesSRTP {
+		if pc.sdesLocalCryptos, err = newSDESLocalCryptos(); err != nil {
+			return nil, &rtcerr.UnknownError{Err: err}
+		}
+	}
+
 	pc.iceGatherer, err = pc.createICEGatherer()
 	if err != nil {
 		return nil, err
@@ -209,6 +224,12 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 			}
 			pc.configuration.Certificates = append(pc.configuration.Certificates, x509Cert)
 		}
+	} else if rotator := pc.api.settingEngine.certificateRotator; rotator != nil {
+		certificates, err := rotator.Certificates()
+		if err != nil {
+			return &rtcerr.UnknownError{Err: err}
+		}
+		pc.configuration.Certificates = certificates
 	} else {
 		sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 		if err != nil {
@@ -303,6 +324,16 @@ func (pc *PeerConnection) onNegotiationNeeded() {
 }
 
 func (pc *PeerConnection) negotiationNeededOp() {
+	// non-canon, reset needed state machine and run again if there was a request
+	defer func() {
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+		if pc.negotiationNeededState == negotiationNeededStateQueue {
+			defer pc.onNegotiationNeeded()
+		}
+		pc.negotiationNeededState = negotiationNeededStateEmpty
+	}()
+
 	// Don't run NegotiatedNeeded checks if OnNegotiationNeeded is not set
 	if handler, ok := pc.onNegotiationNeededHandler.Load().(func()); !ok || handler == nil {
 		return
@@ -319,16 +350,6 @@ func (pc *PeerConnection) negotiationNeededOp() {
 		return
 	}
 
-	// non-canon, run again if there was a request
-	defer func() {
-		pc.mu.Lock()
-		defer pc.mu.Unlock()
-		if pc.negotiationNeededState == negotiationNeededStateQueue {
-			defer pc.onNegotiationNeeded()
-		}
-		pc.negotiationNeededState = negotiationNeededStateEmpty
-	}()
-
 	// Step 2.3
 	if pc.SignalingState() != SignalingStateStable {
 		return
@@ -356,7 +377,12 @@ func (pc *PeerConnection) negotiationNeededOp() {
 
 func (pc *PeerConnection) checkNegotiationNeeded() bool { //nolint:gocognit
 	// To check if negotiation is needed for connection, perform the following checks:
-	// Skip 1, 2 steps
+	// Skip step 1
+	// Step 2
+	if pc.iceRestartRequested.get() {
+		return true
+	}
+
 	// Step 3
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -494,6 +520,10 @@ func (pc *PeerConnection) onConnectionStateChange(cs PeerConnectionState) {
 }
 
 // SetConfiguration updates the configuration of this PeerConnection object.
+// Once candidate gathering has started, changed ICEServers and
+// ICETransportPolicy take effect with the next ICE restart, e.g.
+// CreateOffer with OfferOptions.ICERestart. The restart gathers with a new
+// ICE agent and keeps the DTLS association.
 func (pc *PeerConnection) SetConfiguration(configuration Configuration) error { //nolint:gocognit
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-setconfiguration (step #2)
 	if pc.isClosed.get() {
@@ -548,20 +578,30 @@ func (pc *PeerConnection) SetConfiguration(configuration Configuration) error {
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #8)
+	iceTransportPolicy := pc.configuration.ICETransportPolicy
 	if configuration.ICETransportPolicy != ICETransportPolicy(Unknown) {
-		pc.configuration.ICETransportPolicy = configuration.ICETransportPolicy
+		iceTransportPolicy = configuration.ICETransportPolicy
 	}
 
 	// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11)
-	if len(configuration.ICEServers) > 0 {
+	iceServers := pc.configuration.ICEServers
+	if sanitizedICEServers := configuration.getICEServers(); len(sanitizedICEServers) > 0 {
 		// https://www.w3.org/TR/webrtc/#set-the-configuration (step #11.3)
-		for _, server := range configuration.ICEServers {
+		for _, server := range sanitizedICEServers {
 			if err := server.validate(); err != nil {
 				return err
 			}
 		}
-		pc.configuration.ICEServers = configuration.ICEServers
+		iceServers = sanitizedICEServers
 	}
+
+	// The new servers are used by the next gathering, which for an already
+	// gathering PeerConnection means the next ICE restart.
+	if err := pc.iceGatherer.setICEServers(iceServers, iceTransportPolicy); err != nil {
+		return err
+	}
+	pc.configuration.ICETransportPolicy = iceTransportPolicy
+	pc.configuration.ICEServers = iceServers
 	return nil
 }
 
@@ -607,8 +647,12 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
-	if options != nil && options.ICERestart {
-		if err := pc.iceTransport.restart(); err != nil {
+	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-restartice
+	// A restart requested before the first offer is a no-op, the credentials are new anyway
+	iceRestartRequested := pc.iceRestartRequested.swap(false)
+	if (options != nil && options.ICERestart) || (iceRestartRequested && pc.iceGatherer.getAgent() != nil) {
+		if err := pc.iceTransport.Restart(); err != nil {
+			pc.iceRestartRequested.set(iceRestartRequested)
 			return SessionDescription{}, err
 		}
 	}
@@ -778,6 +822,7 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 			pc.log.Warnf("OnConnectionStateChange: unhandled ICE state: %s", state)
 			return
 		}
+		pc.updateICEAutoRestart(cs)
 		pc.onICEConnectionStateChange(cs)
 		pc.updateConnectionState(cs, pc.dtlsTransport.State())
 	})
@@ -785,6 +830,57 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 	return t
 }
 
+// RestartICE requests an ICE restart. OnNegotiationNeeded fires and the next
+// offer created by CreateOffer restarts ICE, as if OfferOptions.ICERestart was set.
+// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-restartice
+func (pc *PeerConnection) RestartICE() {
+	if pc.isClosed.get() {
+		return
+	}
+
+	pc.iceRestartRequested.set(true)
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onNegotiationNeeded()
+}
+
+// updateICEAutoRestart arms the automatic ICE restart configured with
+// SettingEngine.SetICEAutoRestartTimeout when ICE is disconnected or failed,
+// and disarms it for every other state
+func (pc *PeerConnection) updateICEAutoRestart(state ICEConnectionState) {
+	timeout := pc.api.settingEngine.timeout.ICEAutoRestartTimeout
+	if timeout == 0 {
+		return
+	}
+
+	pc.iceAutoRestartLock.Lock()
+	defer pc.iceAutoRestartLock.Unlock()
+
+	switch state {
+	case ICEConnectionStateDisconnected, ICEConnectionStateFailed:
+		if pc.iceAutoRestartTimer != nil {
+			return
+		}
+
+		// The timer is kept after firing, so ICE is only restarted again once it recovered in between
+		pc.iceAutoRestartTimer = time.AfterFunc(timeout, func() {
+			// ICE may have recovered while the timer fired
+			if state := pc.ICEConnectionState(); state != ICEConnectionStateDisconnected && state != ICEConnectionStateFailed {
+				return
+			}
+
+			pc.log.Infof("ICE connection has not recovered after %s, restarting ICE", timeout)
+			pc.RestartICE()
+		})
+	default:
+		if pc.iceAutoRestartTimer != nil {
+			pc.iceAutoRestartTimer.Stop()
+			pc.iceAutoRestartTimer = nil
+		}
+	}
+}
+
 // CreateAnswer starts the PeerConnection and generates the localDescription
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
@@ -811,6 +907,12 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 			connectionRole = connectionRoleFromDtlsRole(DTLSRoleServer)
 		}
 	}
+
+	// Keep the role of the current DTLS association, answering with another
+	// role would make the remote restart DTLS
+	if role := pc.dtlsTransport.associationRole(); role != DTLSRoleAuto && dtlsRoleFromRemoteSDP(remoteDesc.parsed) == DTLSRoleAuto {
+		connectionRole = connectionRoleFromDtlsRole(role)
+	}
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
@@ -1123,7 +1225,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	if isRenegotation && pc.iceTransport.haveRemoteCredentialsChange(remoteUfrag, remotePwd) {
 		// An ICE Restart only happens implicitly for a SetRemoteDescription of type offer
 		if !weOffer {
-			if err = pc.iceTransport.restart(); err != nil {
+			if err = pc.iceTransport.Restart(); err != nil {
 				return err
 			}
 		}
@@ -1142,6 +1244,36 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 
 	if isRenegotation {
+		if pc.sdesLocalCryptos != nil {
+			// A new a=crypto attribute rekeys SRTP, RFC 4568 Section 7.1.4
+			rekey, err := pc.selectSDESCrypto(desc.parsed, weOffer)
+			if err != nil {
+				return err
+			}
+			if rekey {
+				pc.ops.Enqueue(func() {
+					pc.rekeySDES()
+				})
+			}
+		} else {
+			fingerprints, err := extractFingerprints(desc.parsed)
+			switch {
+			case errors.Is(err, ErrSessionDescriptionNoFingerprint):
+				// A re-offer may omit a=fingerprint, the DTLS association is kept
+			case err != nil:
+				return err
+			default:
+				// The remote regenerated its certificate or changed its role,
+				// the DTLS association has to be restarted
+				dtlsParameters := DTLSParameters{Role: dtlsRoleFromRemoteSDP(desc.parsed), Fingerprints: fingerprints}
+				if pc.dtlsTransport.needsRestart(dtlsParameters) {
+					pc.ops.Enqueue(func() {
+						pc.restartDTLS(dtlsParameters)
+					})
+				}
+			}
+		}
+
 		if weOffer {
 			if err = pc.startRTPSenders(currentTransceivers); err != nil {
 				return err
@@ -1156,8 +1288,12 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 
 	remoteIsLite := isIceLiteSet(desc.parsed)
 
-	fingerprint, fingerprintHash, err := extractFingerprint(desc.parsed)
-	if err != nil {
+	var fingerprints []DTLSFingerprint
+	if pc.sdesLocalCryptos != nil {
+		if _, err = pc.selectSDESCrypto(desc.parsed, weOffer); err != nil {
+			return err
+		}
+	} else if fingerprints, err = extractFingerprints(desc.parsed); err != nil {
 		return err
 	}
 
@@ -1180,7 +1316,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 
 	pc.ops.Enqueue(func() {
-		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), remoteUfrag, remotePwd, fingerprint, fingerprintHash)
+		pc.startTransports(iceRole, dtlsRoleFromRemoteSDP(desc.parsed), remoteUfrag, remotePwd, fingerprints)
 		if weOffer {
 			pc.startRTP(false, &desc, currentTransceivers)
 		}
@@ -1852,6 +1988,10 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
 
+	if pc.sdesLocalCryptos != nil {
+		return nil, &rtcerr.InvalidStateError{Err: errSDESDataChannel}
+	}
+
 	params := &DataChannelParameters{
 		Label:   label,
 		Ordered: true,
@@ -1925,6 +2065,85 @@ func (pc *PeerConnection) CreateDataChannel(label string, options *DataChannelIn
 	return d, nil
 }
 
+// CreateDataChannelsFromLayout creates the pre-negotiated DataChannels described
+// by layout, returned in layout order. Both peers should call it with the same
+// layout so the IDs of the channels match. It fails if an ID of the layout is
+// already used by a DataChannel of this PeerConnection. No DataChannel is
+// created if an error is returned.
+func (pc *PeerConnection) CreateDataChannelsFromLayout(layout DataChannelLayout) ([]*DataChannel, error) {
+	if pc.isClosed.get() {
+		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+	}
+
+	params, err := layout.Compile(pc.sctpTransport.MaxChannels())
+	if err != nil {
+		return nil, err
+	}
+
+	// Allocating around existing IDs would make the peers disagree on the
+	// layout, so a collision is an error
+	pc.sctpTransport.lock.RLock()
+	for _, d := range pc.sctpTransport.dataChannels {
+		id := d.ID()
+		if id == nil {
+			continue
+		}
+		for i := range params {
+			if *params[i].ID == *id {
+				pc.sctpTransport.lock.RUnlock()
+				return nil, &rtcerr.TypeError{Err: ErrDataChannelLayoutDuplicateID}
+			}
+		}
+	}
+	pc.sctpTransport.lock.RUnlock()
+
+	negotiated := true
+	dataChannels := make([]*DataChannel, 0, len(params))
+	for i := range params {
+		d, err := pc.CreateDataChannel(params[i].Label, &DataChannelInit{
+			Ordered:           &params[i].Ordered,
+			MaxPacketLifeTime: params[i].MaxPacketLifeTime,
+			MaxRetransmits:    params[i].MaxRetransmits,
+			Protocol:          &params[i].Protocol,
+			Negotiated:        &negotiated,
+			ID:                params[i].ID,
+		})
+		if err != nil {
+			pc.removeDataChannels(dataChannels)
+			return nil, err
+		}
+		dataChannels = append(dataChannels, d)
+	}
+
+	return dataChannels, nil
+}
+
+// removeDataChannels closes DataChannels that were created by a failed call
+// and forgets them, so their IDs can be used again
+func (pc *PeerConnection) removeDataChannels(dataChannels []*DataChannel) {
+	for _, d := range dataChannels {
+		if err := d.Close(); err != nil {
+			pc.log.Warnf("Failed to close DataChannel %s: %s", d.Label(), err)
+		}
+	}
+
+	pc.sctpTransport.lock.Lock()
+	defer pc.sctpTransport.lock.Unlock()
+
+	remaining := pc.sctpTransport.dataChannels[:0]
+	for _, existing := range pc.sctpTransport.dataChannels {
+		removed := false
+		for _, d := range dataChannels {
+			removed = removed || existing == d
+		}
+		if !removed {
+			remaining = append(remaining, existing)
+		}
+	}
+	pc.sctpTransport.dataChannels = remaining
+	pc.sctpTransport.dataChannelsRequested -= uint32(len(dataChannels))
+}
+
 // SetIdentityProvider is used to configure an identity provider to generate identity assertions
 func (pc *PeerConnection) SetIdentityProvider(provider string) error {
 	return errPeerConnSetIdentityProviderNotImplemented
@@ -2102,7 +2321,7 @@ func (pc *PeerConnection) GetStats() StatsReport {
 		pc.iceGatherer.collectStats(statsCollector)
 	}
 	if pc.iceTransport != nil {
-		pc.iceTransport.collectStats(statsCollector)
+		pc.iceTransport.collectStats(statsCollector, pc.dtlsTransport)
 	}
 
 	pc.sctpTransport.lock.Lock()
@@ -2148,7 +2367,7 @@ func (pc *PeerConnection) GetStats() StatsReport {
 }
 
 // Start all transports. PeerConnection now has enough state
-func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteUfrag, remotePwd, fingerprint, fingerprintHash string) {
+func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, remoteUfrag, remotePwd string, fingerprints []DTLSFingerprint) {
 	// Start the ice transport
 	err := pc.iceTransport.Start(
 		pc.iceGatherer,
@@ -2164,11 +2383,15 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 		return
 	}
 
-	// Start the dtls transport
-	err = pc.dtlsTransport.Start(DTLSParameters{
-		Role:         dtlsRole,
-		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
-	})
+	// Start the dtls transport, or only SRTP if it is keyed with SDES
+	if local, remote, ok := pc.sdesCryptos(); ok {
+		err = pc.dtlsTransport.startSDES(local, remote)
+	} else {
+		err = pc.dtlsTransport.Start(DTLSParameters{
+			Role:         dtlsRole,
+			Fingerprints: fingerprints,
+		})
+	}
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
@@ -2176,6 +2399,87 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 	}
 }
 
+// restartDTLS starts a new DTLS association and moves the RTPSenders and
+// RTPReceivers to its SRTP sessions. The SCTP association can't survive the
+// restart, so its DataChannels are closed.
+func (pc *PeerConnection) restartDTLS(dtlsParameters DTLSParameters) {
+	pc.log.Infof("Restarting DTLS after the remote changed its certificate or role")
+
+	// The SCTP association can't survive the DTLS association, it is started
+	// again over the new one by startRTP of the ongoing negotiation
+	if err := pc.sctpTransport.Stop(); err != nil {
+		pc.log.Warnf("Failed to stop SCTPTransport before DTLS restart: %s", err)
+	}
+	pc.sctpTransport.reset()
+
+	// The SRTP sessions are cleared before the senders drop their streams,
+	// so a concurrent write can't open a stream on the closing session
+	dtlsParameters, err := pc.dtlsTransport.stopForRestart(dtlsParameters)
+	if err != nil {
+		pc.log.Warnf("Failed to restart DTLS: %s", err)
+		return
+	}
+
+	senders := pc.GetSenders()
+	for _, sender := range senders {
+		sender.resetSRTPStreams()
+	}
+
+	err = pc.dtlsTransport.Start(dtlsParameters)
+	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	if err != nil {
+		pc.log.Warnf("Failed to restart DTLS: %s", err)
+		return
+	}
+
+	pc.rebindSRTPStreams(senders)
+}
+
+// rekeySDES keys new SRTP sessions with the selected a=crypto attributes and
+// moves the RTPSenders and RTPReceivers to them
+func (pc *PeerConnection) rekeySDES() {
+	local, remote, ok := pc.sdesCryptos()
+	if !ok {
+		return
+	}
+
+	pc.log.Infof("Rekeying SRTP after the remote changed its a=crypto attribute")
+
+	if err := pc.dtlsTransport.stopSDES(); err != nil {
+		pc.log.Warnf("Failed to rekey SRTP: %s", err)
+		return
+	}
+
+	senders := pc.GetSenders()
+	for _, sender := range senders {
+		sender.resetSRTPStreams()
+	}
+
+	err := pc.dtlsTransport.startSDES(local, remote)
+	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
+	if err != nil {
+		pc.log.Warnf("Failed to rekey SRTP: %s", err)
+		return
+	}
+
+	pc.rebindSRTPStreams(senders)
+}
+
+// rebindSRTPStreams moves the RTPSenders and RTPReceivers to new SRTP sessions
+func (pc *PeerConnection) rebindSRTPStreams(senders []*RTPSender) {
+	for _, sender := range senders {
+		if err := sender.rebindSRTPStreams(); err != nil {
+			pc.log.Warnf("Failed to rebind RTPSender to new SRTP session: %s", err)
+		}
+	}
+	for _, receiver := range pc.GetReceivers() {
+		if err := receiver.rebindStreams(); err != nil {
+			pc.log.Warnf("Failed to rebind RTPReceiver to new SRTP session: %s", err)
+		}
+	}
+	pc.undeclaredMediaProcessor()
+}
+
 // nolint: gocognit
 func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDescription, currentTransceivers []*RTPTransceiver) {
 	if !isRenegotiation {
@@ -2251,12 +2555,20 @@ func (pc *PeerConnection) generateUnmatchedSDP(transceivers []*RTPTransceiver, u
 		}
 	}
 
-	dtlsFingerprints, err := pc.configuration.Certificates[0].GetFingerprints()
+	dtlsParams, err := pc.dtlsTransport.GetLocalParameters()
 	if err != nil {
 		return nil, err
 	}
+	dtlsFingerprints := dtlsParams.Fingerprints
+	if pc.sdesLocalCryptos != nil {
+		dtlsFingerprints = nil
+	}
 
-	return populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, true, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	d, err = populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, true, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	if err == nil && pc.sdesLocalCryptos != nil {
+		pc.populateSDESCryptos(d, nil)
+	}
+	return d, err
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -2378,12 +2690,20 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 		pc.log.Info("Plan-B Offer detected; responding with Plan-B Answer")
 	}
 
-	dtlsFingerprints, err := pc.configuration.Certificates[0].GetFingerprints()
+	dtlsParams, err := pc.dtlsTransport.GetLocalParameters()
 	if err != nil {
 		return nil, err
 	}
+	dtlsFingerprints := dtlsParams.Fingerprints
+	if pc.sdesLocalCryptos != nil {
+		dtlsFingerprints = nil
+	}
 
-	return populateSDP(d, detectedPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, isExtmapAllowMixed, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	d, err = populateSDP(d, detectedPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, isExtmapAllowMixed, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState())
+	if err == nil && pc.sdesLocalCryptos != nil {
+		pc.populateSDESCryptos(d, remoteDescription)
+	}
+	return d, err
 }
 
 func (pc *PeerConnection) setGatherCompleteHandler(handler func()) {
@@ -12,6 +12,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"math/big"
+	"net"
 	"reflect"
 	"regexp"
 	"strings"
@@ -23,6 +24,7 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/transport/test"
 	"github.com/pion/transport/vnet"
+	"github.com/pion/turn/v2"
 	"github.com/pion/webrtc/v3/internal/util"
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
 	"github.com/stretchr/testify/assert"
@@ -266,6 +268,24 @@ func TestPeerConnection_SetConfiguration_Go(t *testing.T) {
 			},
 			wantErr: &rtcerr.InvalidAccessError{Err: ErrNoTurnCredentials},
 		},
+		{
+			name: "update ICEServers URLs after gathering started",
+			init: func() (*PeerConnection, error) {
+				pc, err := api.NewPeerConnection(Configuration{
+					ICEServers: []ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}},
+				})
+				if err != nil {
+					return pc, err
+				}
+
+				_, err = pc.CreateOffer(nil)
+				return pc, err
+			},
+			config: Configuration{
+				ICEServers: []ICEServer{{URLs: []string{"stun:stun1.l.google.com:19302"}}},
+			},
+			wantErr: nil,
+		},
 	} {
 		pc, err := test.init()
 		if err != nil {
@@ -1013,6 +1033,93 @@ func TestICERestart_Error_Handling(t *testing.T) {
 	closePairNow(t, offerPeerConnection, answerPeerConnection)
 }
 
+// Assert that SetICEAutoRestartTimeout requests an ICE restart after ICE failed
+func TestICERestart_Auto(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	extractICEUfrag := func(sdp string) string {
+		for _, line := range strings.Split(sdp, "\r\n") {
+			if strings.HasPrefix(line, "a=ice-ufrag:") {
+				return line
+			}
+		}
+		return ""
+	}
+
+	offerPeerConnection, answerPeerConnection, wan := createVNetPair(t)
+	offerPeerConnection.api.settingEngine.SetICEAutoRestartTimeout(100 * time.Millisecond)
+
+	iceStates := make(chan ICEConnectionState, 100)
+	offerPeerConnection.OnICEConnectionStateChange(func(i ICEConnectionState) { iceStates <- i })
+	blockUntilICEState := func(wantedState ICEConnectionState) {
+		for i := range iceStates {
+			if i == wantedState {
+				return
+			}
+		}
+	}
+
+	keepPackets := &atomicBool{}
+	keepPackets.set(true)
+	wan.AddChunkFilter(func(c vnet.Chunk) bool {
+		return keepPackets.get()
+	})
+
+	// Wait for SCTP to be established, so no transport is still being started
+	dataChannelOpened := make(chan struct{})
+	answerPeerConnection.OnDataChannel(func(d *DataChannel) {
+		d.OnOpen(func() { close(dataChannelOpened) })
+	})
+
+	assert.NoError(t, signalPair(offerPeerConnection, answerPeerConnection))
+	<-dataChannelOpened
+	firstOffer := offerPeerConnection.LocalDescription().SDP
+
+	offerPeerConnection.OnICECandidate(func(c *ICECandidate) {
+		if c != nil {
+			assert.NoError(t, answerPeerConnection.AddICECandidate(c.ToJSON()))
+		}
+	})
+	answerPeerConnection.OnICECandidate(func(c *ICECandidate) {
+		if c != nil {
+			assert.NoError(t, offerPeerConnection.AddICECandidate(c.ToJSON()))
+		}
+	})
+
+	negotiationNeeded := make(chan struct{}, 1)
+	offerPeerConnection.OnNegotiationNeeded(func() {
+		negotiationNeeded <- struct{}{}
+	})
+
+	// Drop all packets until ICE fails and the restart is requested
+	keepPackets.set(false)
+	blockUntilICEState(ICEConnectionStateFailed)
+	<-negotiationNeeded
+	keepPackets.set(true)
+
+	// No OfferOptions are needed, the requested restart is part of the next offer
+	offer, err := offerPeerConnection.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, extractICEUfrag(firstOffer), extractICEUfrag(offer.SDP))
+
+	assert.NoError(t, offerPeerConnection.SetLocalDescription(offer))
+	assert.NoError(t, answerPeerConnection.SetRemoteDescription(offer))
+
+	answer, err := answerPeerConnection.CreateAnswer(nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, answerPeerConnection.SetLocalDescription(answer))
+	assert.NoError(t, offerPeerConnection.SetRemoteDescription(answer))
+	blockUntilICEState(ICEConnectionStateConnected)
+
+	assert.NoError(t, wan.Stop())
+	closePairNow(t, offerPeerConnection, answerPeerConnection)
+}
+
 type trackRecords struct {
 	mu               sync.Mutex
 	trackIDs         map[string]struct{}
@@ -1584,3 +1691,185 @@ a=ssrc:1455629982 cname:{61fd3093-0326-4b12-8258-86bdc1fe677a}
 
 	assert.NoError(t, peerConnection.Close())
 }
+
+// Assert that TURN credentials rotated with SetConfiguration are used to
+// gather relay candidates after the next ICE restart
+func TestPeerConnection_SetConfiguration_RotateTURNCredentials(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const realm = "pion.ly"
+
+	// The TURN server only accepts the current credentials
+	var credentialsLock sync.Mutex
+	currentUsername := "initial"
+	usedUsernames := []string{}
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: realm,
+		AuthHandler: func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+			credentialsLock.Lock()
+			defer credentialsLock.Unlock()
+
+			usedUsernames = append(usedUsernames, username)
+			if username != currentUsername {
+				return nil, false
+			}
+			return turn.GenerateAuthKey(username, realm, username+"-password"), true
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{{
+			PacketConn: udpListener,
+			RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+				RelayAddress: net.ParseIP("127.0.0.1"),
+				Address:      "127.0.0.1",
+			},
+		}},
+	})
+	assert.NoError(t, err)
+
+	turnServer := func(username string) []ICEServer {
+		return []ICEServer{{
+			URLs:       []string{"turn:" + udpListener.LocalAddr().String() + "?transport=udp"},
+			Username:   username,
+			Credential: username + "-password",
+		}}
+	}
+
+	pcOffer, err := NewPeerConnection(Configuration{
+		ICEServers:         turnServer("initial"),
+		ICETransportPolicy: ICETransportPolicyRelay,
+	})
+	assert.NoError(t, err)
+
+	pcAnswer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Contains(t, pcOffer.LocalDescription().SDP, "typ relay")
+
+	// Rotate the credentials, new allocations with the old ones are rejected
+	credentialsLock.Lock()
+	currentUsername = "rotated"
+	credentialsLock.Unlock()
+
+	assert.NoError(t, pcOffer.SetConfiguration(Configuration{
+		ICEServers:         turnServer("rotated"),
+		ICETransportPolicy: ICETransportPolicyRelay,
+	}))
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	gatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-gatheringComplete
+
+	assert.Contains(t, pcOffer.LocalDescription().SDP, "typ relay")
+	credentialsLock.Lock()
+	assert.Contains(t, usedUsernames, "rotated")
+	credentialsLock.Unlock()
+
+	closePairNow(t, pcOffer, pcAnswer)
+	assert.NoError(t, server.Close())
+}
+
+func TestPeerConnection_SetConfiguration_ICERestartWithNewServers(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	const realm = "pion.ly"
+
+	udpListener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server, err := turn.NewServer(turn.ServerConfig{
+		Realm: realm,
+		AuthHandler: func(username, realm string, srcAddr net.Addr) ([]byte, bool) {
+			return turn.GenerateAuthKey(username, realm, "password"), true
+		},
+		PacketConnConfigs: []turn.PacketConnConfig{{
+			PacketConn: udpListener,
+			RelayAddressGenerator: &turn.RelayAddressGeneratorStatic{
+				RelayAddress: net.ParseIP("127.0.0.1"),
+				Address:      "127.0.0.1",
+			},
+		}},
+	})
+	assert.NoError(t, err)
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	messages := make(chan string, 10)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messages <- string(msg.Data)
+		})
+	})
+
+	dataChannelOpened, dataChannelOpenedFunc := context.WithCancel(context.Background())
+	dataChannel, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	dataChannel.OnOpen(dataChannelOpenedFunc)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	<-dataChannelOpened.Done()
+
+	// Switch the running PeerConnection to relay only through a new server
+	iceServers := []ICEServer{{
+		URLs:       []string{"turn:" + udpListener.LocalAddr().String() + "?transport=udp"},
+		Username:   "user",
+		Credential: "password",
+	}}
+	assert.NoError(t, pcOffer.SetConfiguration(Configuration{
+		ICEServers:         iceServers,
+		ICETransportPolicy: ICETransportPolicyRelay,
+	}))
+	assert.Equal(t, iceServers, pcOffer.GetConfiguration().ICEServers)
+	assert.Equal(t, ICETransportPolicyRelay, pcOffer.GetConfiguration().ICETransportPolicy)
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	offerGatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+
+	assert.Contains(t, pcOffer.LocalDescription().SDP, "typ relay")
+	assert.NotContains(t, pcOffer.LocalDescription().SDP, "typ host")
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	answerGatheringComplete := GatheringCompletePromise(pcAnswer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+
+	// The existing DTLS association and DataChannel move to the relayed pair
+	for {
+		pair, err := pcOffer.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+		assert.NoError(t, err)
+		if pair != nil && pair.Local.Typ == ICECandidateTypeRelay {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	assert.NoError(t, dataChannel.SendText("relayed"))
+	for msg := range messages {
+		if msg == "relayed" {
+			break
+		}
+	}
+
+	closePairNow(t, pcOffer, pcAnswer)
+	assert.NoError(t, server.Close())
+}
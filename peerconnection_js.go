@@ -387,6 +387,12 @@ func (pc *PeerConnection) SetIdentityProvider(provider string) (err error) {
 	return nil
 }
 
+// RestartICE requests an ICE restart, the next offer created by CreateOffer
+// restarts ICE
+func (pc *PeerConnection) RestartICE() {
+	pc.underlying.Call("restartIce")
+}
+
 // Close ends the PeerConnection
 func (pc *PeerConnection) Close() (err error) {
 	defer func() {
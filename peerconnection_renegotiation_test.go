@@ -1145,3 +1145,155 @@ func TestPeerConnection_Renegotiation_Simulcast(t *testing.T) {
 		closePairNow(t, pcOffer, pcAnswer)
 	})
 }
+
+// Assert that a renegotiation with a new certificate restarts DTLS, like
+// when the remote application restarted but kept the signaling session
+func TestPeerConnection_Renegotiation_DTLSRestart(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	vp8Track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "foo", "bar")
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTrack(vp8Track)
+	assert.NoError(t, err)
+
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(*TrackRemote, *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	dcBeforeOpened, dcBeforeOpenedFunc := context.WithCancel(context.Background())
+	dcBeforeClosed, dcBeforeClosedFunc := context.WithCancel(context.Background())
+	dcBefore, err := pcOffer.CreateDataChannel("before", nil)
+	assert.NoError(t, err)
+	dcBefore.OnOpen(dcBeforeOpenedFunc)
+	dcBefore.OnClose(dcBeforeClosedFunc)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrackFired.Done(), t, []*TrackLocalStaticSample{vp8Track})
+	<-dcBeforeOpened.Done()
+	firstCertificate := pcOffer.SCTP().Transport().GetRemoteCertificate()
+
+	// The restarted remote answers with a new certificate
+	pcRestarted, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	onRestartedTrackFired, onRestartedTrackFiredFunc := context.WithCancel(context.Background())
+	pcRestarted.OnTrack(func(*TrackRemote, *RTPReceiver) {
+		onRestartedTrackFiredFunc()
+	})
+	dcAfterReceived, dcAfterReceivedFunc := context.WithCancel(context.Background())
+	pcRestarted.OnDataChannel(func(d *DataChannel) {
+		if d.Label() == "after" {
+			d.OnOpen(dcAfterReceivedFunc)
+		}
+	})
+	connected := untilConnectionState(PeerConnectionStateConnected, pcRestarted)
+
+	offer, err := pcOffer.CreateOffer(&OfferOptions{ICERestart: true})
+	assert.NoError(t, err)
+	offerGatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+
+	assert.NoError(t, pcRestarted.SetRemoteDescription(*pcOffer.LocalDescription()))
+	answer, err := pcRestarted.CreateAnswer(nil)
+	assert.NoError(t, err)
+	answerGatheringComplete := GatheringCompletePromise(pcRestarted)
+	assert.NoError(t, pcRestarted.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcRestarted.LocalDescription()))
+	connected.Wait()
+
+	// The existing RTPSender keeps sending on the new SRTP session
+	sendVideoUntilDone(onRestartedTrackFired.Done(), t, []*TrackLocalStaticSample{vp8Track})
+	assert.NotEqual(t, firstCertificate, pcOffer.SCTP().Transport().GetRemoteCertificate())
+
+	// A write stream opened on a previous SRTP session is replaced
+	srtpSession, err := pcOffer.dtlsTransport.getSRTPSession()
+	assert.NoError(t, err)
+	srtpStream := pcOffer.GetSenders()[0].trackEncodings[0].srtpStream
+	srtpStream.mu.Lock()
+	assert.Equal(t, srtpSession, srtpStream.srtpSession)
+	srtpStream.srtpSession = nil
+	srtpStream.mu.Unlock()
+
+	assert.NoError(t, srtpStream.init(true))
+	srtpStream.mu.Lock()
+	assert.Equal(t, srtpSession, srtpStream.srtpSession)
+	srtpStream.mu.Unlock()
+
+	// DataChannels of the previous SCTP association are closed, new ones
+	// open over the new association
+	<-dcBeforeClosed.Done()
+	dcAfterOpened, dcAfterOpenedFunc := context.WithCancel(context.Background())
+	dcAfter, err := pcOffer.CreateDataChannel("after", nil)
+	assert.NoError(t, err)
+	dcAfter.OnOpen(dcAfterOpenedFunc)
+	<-dcAfterOpened.Done()
+	<-dcAfterReceived.Done()
+
+	assert.NoError(t, pcAnswer.Close())
+	closePairNow(t, pcOffer, pcRestarted)
+}
+
+func TestPeerConnection_Renegotiation_DTLSRestart_NotNeeded(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.DisableCertificateFingerprintVerification(true)
+
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair(Configuration{})
+	assert.NoError(t, err)
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	connected.Wait()
+
+	// Fingerprints aren't verified, only a change of the signaled ones
+	// requires a restart
+	pcOffer.dtlsTransport.lock.RLock()
+	signaled := append([]DTLSFingerprint{}, pcOffer.dtlsTransport.remoteParameters.Fingerprints...)
+	pcOffer.dtlsTransport.lock.RUnlock()
+	assert.NotEmpty(t, signaled)
+	assert.False(t, pcOffer.dtlsTransport.needsRestart(DTLSParameters{Role: DTLSRoleAuto, Fingerprints: signaled}))
+	assert.True(t, pcOffer.dtlsTransport.needsRestart(DTLSParameters{
+		Role:         DTLSRoleAuto,
+		Fingerprints: []DTLSFingerprint{{Algorithm: "sha-256", Value: "00:00"}},
+	}))
+
+	// A re-offer without a=fingerprint keeps the DTLS association
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+
+	var lines []string
+	for _, line := range strings.Split(offer.SDP, "\r\n") {
+		if !strings.HasPrefix(line, "a=fingerprint:") {
+			lines = append(lines, line)
+		}
+	}
+	offer.SDP = strings.Join(lines, "\r\n")
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	assert.NoError(t, pcOffer.SetRemoteDescription(answer))
+
+	pcAnswer.ops.Done()
+	assert.Equal(t, DTLSTransportStateConnected, pcAnswer.dtlsTransport.State())
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -0,0 +1,100 @@
+package dcrpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+)
+
+// Codec serializes requests and responses. Both endpoints of a connection
+// must use the same Codec.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec struct{}
+
+// Marshal returns the JSON encoding of v
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal parses the JSON-encoded data and stores the result in v
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// BinaryCodec passes raw bytes through unchanged. Values must be []byte,
+// a *[]byte or implement encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler.
+type BinaryCodec struct{}
+
+// Marshal returns the binary representation of v
+func (BinaryCodec) Marshal(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return val, nil
+	case *[]byte:
+		return *val, nil
+	case encoding.BinaryMarshaler:
+		return val.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("%w: %T", errCodecUnsupportedType, v)
+	}
+}
+
+// Unmarshal stores data in v
+func (BinaryCodec) Unmarshal(data []byte, v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*val = append([]byte{}, data...)
+		return nil
+	case encoding.BinaryUnmarshaler:
+		return val.UnmarshalBinary(data)
+	default:
+		return fmt.Errorf("%w: %T", errCodecUnsupportedType, v)
+	}
+}
+
+// ProtoMessage is implemented by protobuf messages generated with
+// marshaling methods, such as gogo/protobuf or vtprotobuf.
+type ProtoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal([]byte) error
+}
+
+// ProtoCodec encodes values that implement ProtoMessage. Codecs for other
+// protobuf runtimes can be provided by implementing Codec directly.
+type ProtoCodec struct{}
+
+// Marshal returns the protobuf encoding of v
+func (ProtoCodec) Marshal(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(ProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", errCodecUnsupportedType, v)
+	}
+	return m.Marshal()
+}
+
+// Unmarshal parses the protobuf-encoded data and stores the result in v
+func (ProtoCodec) Unmarshal(data []byte, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+
+	m, ok := v.(ProtoMessage)
+	if !ok {
+		return fmt.Errorf("%w: %T", errCodecUnsupportedType, v)
+	}
+	return m.Unmarshal(data)
+}
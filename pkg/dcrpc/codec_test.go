@@ -0,0 +1,50 @@
+package dcrpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testProtoMessage struct {
+	value string
+}
+
+func (m *testProtoMessage) Marshal() ([]byte, error) {
+	return []byte(m.value), nil
+}
+
+func (m *testProtoMessage) Unmarshal(b []byte) error {
+	m.value = string(b)
+	return nil
+}
+
+func TestBinaryCodec(t *testing.T) {
+	codec := BinaryCodec{}
+
+	raw, err := codec.Marshal([]byte{0x01, 0x02})
+	assert.NoError(t, err)
+
+	var out []byte
+	assert.NoError(t, codec.Unmarshal(raw, &out))
+	assert.Equal(t, []byte{0x01, 0x02}, out)
+
+	_, err = codec.Marshal("string")
+	assert.True(t, errors.Is(err, errCodecUnsupportedType))
+	assert.True(t, errors.Is(codec.Unmarshal(raw, out), errCodecUnsupportedType))
+}
+
+func TestProtoCodec(t *testing.T) {
+	codec := ProtoCodec{}
+
+	raw, err := codec.Marshal(&testProtoMessage{value: "proto"})
+	assert.NoError(t, err)
+
+	out := &testProtoMessage{}
+	assert.NoError(t, codec.Unmarshal(raw, out))
+	assert.Equal(t, "proto", out.value)
+
+	_, err = codec.Marshal(struct{}{})
+	assert.True(t, errors.Is(err, errCodecUnsupportedType))
+}
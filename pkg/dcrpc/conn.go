@@ -0,0 +1,103 @@
+package dcrpc
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pion/datachannel"
+	"github.com/pion/webrtc/v3"
+)
+
+// maxMessageSize is the largest message a DataChannel delivers by default
+const maxMessageSize = 65536
+
+// MessageConn is a message oriented connection. Every WriteMessage call must
+// be delivered as exactly one ReadMessage on the remote side.
+type MessageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage([]byte) error
+	Close() error
+}
+
+type detachedConn struct {
+	rwc    datachannel.ReadWriteCloser
+	buffer []byte
+}
+
+// NewDetachedConn creates a MessageConn from a detached DataChannel
+func NewDetachedConn(rwc datachannel.ReadWriteCloser) MessageConn {
+	return &detachedConn{
+		rwc:    rwc,
+		buffer: make([]byte, maxMessageSize),
+	}
+}
+
+func (c *detachedConn) ReadMessage() ([]byte, error) {
+	n, _, err := c.rwc.ReadDataChannel(c.buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, c.buffer[:n]...), nil
+}
+
+func (c *detachedConn) WriteMessage(msg []byte) error {
+	_, err := c.rwc.WriteDataChannel(msg, false)
+	return err
+}
+
+func (c *detachedConn) Close() error {
+	return c.rwc.Close()
+}
+
+type dataChannelConn struct {
+	dc        *webrtc.DataChannel
+	messages  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewDataChannelConn creates a MessageConn from a DataChannel. It replaces
+// the OnMessage and OnClose handlers of the DataChannel, so it should be
+// created before the DataChannel opens or from its OnOpen handler.
+func NewDataChannelConn(dc *webrtc.DataChannel) MessageConn {
+	c := &dataChannelConn{
+		dc:       dc,
+		messages: make(chan []byte),
+		closed:   make(chan struct{}),
+	}
+
+	dc.OnMessage(func(msg webrtc.DataChannelMessage) {
+		select {
+		case c.messages <- msg.Data:
+		case <-c.closed:
+		}
+	})
+	dc.OnClose(c.markClosed)
+
+	return c
+}
+
+func (c *dataChannelConn) ReadMessage() ([]byte, error) {
+	select {
+	case msg := <-c.messages:
+		return msg, nil
+	case <-c.closed:
+		return nil, io.EOF
+	}
+}
+
+func (c *dataChannelConn) WriteMessage(msg []byte) error {
+	return c.dc.Send(msg)
+}
+
+func (c *dataChannelConn) Close() error {
+	c.markClosed()
+	return c.dc.Close()
+}
+
+func (c *dataChannelConn) markClosed() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
@@ -0,0 +1,561 @@
+// Package dcrpc implements request/response RPC on top of DataChannels
+//
+// Every call is correlated with an ID, so any number of calls can be in flight
+// on one DataChannel at the same time. Calls can be unary or return a stream of
+// responses, and are cancelled on the remote side when their context ends.
+//
+// Streamed responses are queued per call. When a queue is full the Endpoint
+// stops reading from its MessageConn until the Stream is read from, so a
+// remote handler can't make the Endpoint buffer without limit.
+package dcrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/logging"
+)
+
+var (
+	// ErrClosed is returned by calls on an Endpoint that has been closed
+	ErrClosed = errors.New("dcrpc: endpoint closed")
+
+	errFrameTooShort        = errors.New("dcrpc: frame too short")
+	errUnknownFrameType     = errors.New("dcrpc: unknown frame type")
+	errMethodTooLong        = errors.New("dcrpc: method name too long")
+	errCodecUnsupportedType = errors.New("dcrpc: codec does not support type")
+	errUnexpectedFrame      = errors.New("dcrpc: unexpected frame for call")
+)
+
+const (
+	// unknownMethodMessage is the RemoteError message of calls to methods without a handler
+	unknownMethodMessage = "unknown method"
+
+	// duplicateCallIDMessage is the RemoteError message of calls whose ID is
+	// already used by a running call
+	duplicateCallIDMessage = "duplicate call id"
+
+	defaultStreamBufferSize = 64
+)
+
+// RemoteError is returned by calls whose handler returned an error on the
+// remote endpoint
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("dcrpc: remote error: %s", e.Message)
+}
+
+// Request is a call received by a handler
+type Request struct {
+	Method string
+
+	payload []byte
+	codec   Codec
+}
+
+// Decode decodes the request into v
+func (r *Request) Decode(v interface{}) error {
+	return r.codec.Unmarshal(r.payload, v)
+}
+
+// Handler serves unary calls. The returned value is encoded and sent as the
+// response, unless an error is returned.
+type Handler func(ctx context.Context, req *Request) (interface{}, error)
+
+// StreamHandler serves streaming calls. Every value passed to send is
+// delivered as one response. The stream ends when the handler returns.
+type StreamHandler func(ctx context.Context, req *Request, send func(v interface{}) error) error
+
+type handler struct {
+	unary  Handler
+	stream StreamHandler
+}
+
+// Config collects the arguments to Endpoint construction into
+// a single structure
+type Config struct {
+	// Codec encodes requests and responses. Defaults to JSONCodec.
+	Codec Codec
+
+	// CallTimeout is applied to calls whose context has no deadline.
+	// Leave this 0 to wait for responses indefinitely.
+	CallTimeout time.Duration
+
+	// StreamBufferSize is how many responses of a call are queued before
+	// the Endpoint stops reading until the Stream is read from.
+	// Defaults to 64.
+	StreamBufferSize int
+
+	LoggerFactory logging.LoggerFactory
+}
+
+// Endpoint sends and serves calls over a MessageConn. Both sides of a
+// connection use an Endpoint, and either side can make calls.
+type Endpoint struct {
+	conn             MessageConn
+	codec            Codec
+	callTimeout      time.Duration
+	streamBufferSize int
+	log              logging.LeveledLogger
+
+	nextCallID uint32
+	writeMu    sync.Mutex
+
+	mu       sync.Mutex
+	calls    map[uint32]*call
+	handlers map[string]handler
+	serving  map[uint32]context.CancelFunc
+	err      error
+
+	handlersWG   sync.WaitGroup
+	closed       chan struct{}
+	readLoopDone chan struct{}
+}
+
+// NewEndpoint creates an Endpoint and starts reading from conn
+func NewEndpoint(conn MessageConn, config Config) *Endpoint {
+	if config.Codec == nil {
+		config.Codec = JSONCodec{}
+	}
+	if config.StreamBufferSize <= 0 {
+		config.StreamBufferSize = defaultStreamBufferSize
+	}
+	if config.LoggerFactory == nil {
+		config.LoggerFactory = logging.NewDefaultLoggerFactory()
+	}
+
+	e := &Endpoint{
+		conn:             conn,
+		codec:            config.Codec,
+		callTimeout:      config.CallTimeout,
+		streamBufferSize: config.StreamBufferSize,
+		log:              config.LoggerFactory.NewLogger("dcrpc"),
+		calls:            map[uint32]*call{},
+		handlers:         map[string]handler{},
+		serving:          map[uint32]context.CancelFunc{},
+		closed:           make(chan struct{}),
+		readLoopDone:     make(chan struct{}),
+	}
+
+	go e.readLoop()
+
+	return e
+}
+
+// Handle registers the handler for unary calls of method
+func (e *Endpoint) Handle(method string, h Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[method] = handler{unary: h}
+}
+
+// HandleStream registers the handler for streaming calls of method
+func (e *Endpoint) HandleStream(method string, h StreamHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.handlers[method] = handler{stream: h}
+}
+
+// Call invokes method on the remote Endpoint and decodes the response into
+// resp. If ctx ends before the response arrives the call is cancelled on the
+// remote Endpoint and the context error is returned.
+func (e *Endpoint) Call(ctx context.Context, method string, req, resp interface{}) error {
+	ctx, cancel := e.withCallTimeout(ctx)
+	defer cancel()
+
+	c, err := e.startCall(ctx, method, req)
+	if err != nil {
+		return err
+	}
+	defer e.finishCall(c)
+
+	f, err := c.next(ctx, e.closed)
+	switch {
+	case err != nil:
+		return e.callError(c, err)
+	case f.typ == frameTypeResponse:
+		return e.codec.Unmarshal(f.payload, resp)
+	case f.typ == frameTypeError:
+		return &RemoteError{Message: string(f.payload)}
+	default:
+		return errUnexpectedFrame
+	}
+}
+
+// CallStream invokes a streaming method on the remote Endpoint. Responses
+// are read with Stream.Recv. The call is cancelled on the remote Endpoint if
+// ctx ends or the Stream is closed before the remote handler returns.
+// Read the Stream until it ends or close it: once Config.StreamBufferSize
+// responses are queued the Endpoint stops reading until Recv is called.
+func (e *Endpoint) CallStream(ctx context.Context, method string, req interface{}) (*Stream, error) {
+	ctx, cancel := e.withCallTimeout(ctx)
+
+	c, err := e.startCall(ctx, method, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Stream{endpoint: e, call: c, ctx: ctx, cancel: cancel}, nil
+}
+
+// Close closes the Endpoint and its MessageConn. Pending calls fail with
+// ErrClosed. Close waits for running handlers to return after cancelling
+// their contexts, so a handler must not call it, it would wait for itself.
+// A handler that wants to close the Endpoint can call Close in a new goroutine.
+func (e *Endpoint) Close() error {
+	err := e.close()
+	<-e.readLoopDone
+	e.handlersWG.Wait()
+
+	return err
+}
+
+func (e *Endpoint) close() error {
+	e.mu.Lock()
+	if e.err != nil {
+		e.mu.Unlock()
+		return nil
+	}
+	e.err = ErrClosed
+	close(e.closed)
+	for _, cancel := range e.serving {
+		cancel()
+	}
+	e.mu.Unlock()
+
+	return e.conn.Close()
+}
+
+func (e *Endpoint) closeErr() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+func (e *Endpoint) withCallTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline && e.callTimeout > 0 {
+		return context.WithTimeout(ctx, e.callTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+func (e *Endpoint) startCall(ctx context.Context, method string, req interface{}) (*call, error) {
+	payload, err := e.codec.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &call{
+		frames:    make(chan frame, e.streamBufferSize),
+		cancelled: ctx.Done(),
+		finished:  make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	if e.err != nil {
+		e.mu.Unlock()
+		return nil, e.err
+	}
+	// Skip IDs still used by long running calls once the counter wraps around
+	for {
+		c.id = atomic.AddUint32(&e.nextCallID, 1)
+		if _, inUse := e.calls[c.id]; !inUse {
+			break
+		}
+	}
+	e.calls[c.id] = c
+	e.mu.Unlock()
+
+	if err := e.writeFrame(&frame{typ: frameTypeRequest, callID: c.id, method: method, payload: payload}); err != nil {
+		e.finishCall(c)
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (e *Endpoint) finishCall(c *call) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.calls[c.id] != c {
+		return
+	}
+	delete(e.calls, c.id)
+	close(c.finished)
+}
+
+// callError converts the error of a call that ended without a final frame
+func (e *Endpoint) callError(c *call, err error) error {
+	if errors.Is(err, ErrClosed) {
+		return e.closeErr()
+	}
+
+	if writeErr := e.writeFrame(&frame{typ: frameTypeCancel, callID: c.id}); writeErr != nil {
+		e.log.Debugf("failed to cancel call %d: %v", c.id, writeErr)
+	}
+	return err
+}
+
+func (e *Endpoint) writeFrame(f *frame) error {
+	raw, err := f.marshal()
+	if err != nil {
+		return err
+	}
+
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	return e.conn.WriteMessage(raw)
+}
+
+func (e *Endpoint) readLoop() {
+	defer close(e.readLoopDone)
+
+	for {
+		raw, err := e.conn.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				e.log.Debugf("read failed, closing endpoint: %v", err)
+			}
+			if closeErr := e.close(); closeErr != nil {
+				e.log.Debugf("failed to close MessageConn: %v", closeErr)
+			}
+			return
+		}
+
+		f := frame{}
+		if err := f.unmarshal(raw); err != nil {
+			e.log.Warnf("dropping invalid frame: %v", err)
+			continue
+		}
+
+		switch f.typ {
+		case frameTypeRequest:
+			e.serve(f)
+		case frameTypeCancel:
+			e.mu.Lock()
+			if cancel, ok := e.serving[f.callID]; ok {
+				cancel()
+			}
+			e.mu.Unlock()
+		default:
+			e.mu.Lock()
+			c, ok := e.calls[f.callID]
+			e.mu.Unlock()
+			if ok {
+				c.push(f, e.closed)
+			}
+		}
+	}
+}
+
+func (e *Endpoint) serve(f frame) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.mu.Lock()
+	h, ok := e.handlers[f.method]
+	if e.err != nil {
+		e.mu.Unlock()
+		cancel()
+		return
+	}
+	if _, duplicate := e.serving[f.callID]; duplicate {
+		e.mu.Unlock()
+		cancel()
+
+		e.log.Warnf("rejecting call %d, its ID is used by a running call", f.callID)
+		if err := e.writeFrame(&frame{typ: frameTypeError, callID: f.callID, payload: []byte(duplicateCallIDMessage)}); err != nil {
+			e.log.Debugf("failed to reject call %d: %v", f.callID, err)
+		}
+		return
+	}
+	e.serving[f.callID] = cancel
+	e.handlersWG.Add(1)
+	e.mu.Unlock()
+
+	go func() {
+		defer e.handlersWG.Done()
+		defer func() {
+			e.mu.Lock()
+			delete(e.serving, f.callID)
+			e.mu.Unlock()
+			cancel()
+		}()
+
+		if !ok {
+			e.reply(ctx, &frame{typ: frameTypeError, callID: f.callID, payload: []byte(unknownMethodMessage)})
+			return
+		}
+
+		req := &Request{Method: f.method, payload: f.payload, codec: e.codec}
+		if h.unary != nil {
+			e.serveUnary(ctx, f.callID, h.unary, req)
+		} else {
+			e.serveStream(ctx, f.callID, h.stream, req)
+		}
+	}()
+}
+
+func (e *Endpoint) serveUnary(ctx context.Context, callID uint32, h Handler, req *Request) {
+	resp, err := h(ctx, req)
+	if err != nil {
+		e.reply(ctx, &frame{typ: frameTypeError, callID: callID, payload: []byte(err.Error())})
+		return
+	}
+
+	payload, err := e.codec.Marshal(resp)
+	if err != nil {
+		e.reply(ctx, &frame{typ: frameTypeError, callID: callID, payload: []byte(err.Error())})
+		return
+	}
+
+	e.reply(ctx, &frame{typ: frameTypeResponse, callID: callID, payload: payload})
+}
+
+func (e *Endpoint) serveStream(ctx context.Context, callID uint32, h StreamHandler, req *Request) {
+	err := h(ctx, req, func(v interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		payload, err := e.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		return e.writeFrame(&frame{typ: frameTypeStreamItem, callID: callID, payload: payload})
+	})
+	if err != nil {
+		e.reply(ctx, &frame{typ: frameTypeError, callID: callID, payload: []byte(err.Error())})
+		return
+	}
+
+	e.reply(ctx, &frame{typ: frameTypeStreamEnd, callID: callID})
+}
+
+// reply sends the final frame of a call, unless the caller already gave up on it
+func (e *Endpoint) reply(ctx context.Context, f *frame) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := e.writeFrame(f); err != nil {
+		e.log.Debugf("failed to reply to call %d: %v", f.callID, err)
+	}
+}
+
+// Stream is a call with streamed responses
+type Stream struct {
+	endpoint *Endpoint
+	call     *call
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu   sync.Mutex
+	done bool
+	err  error
+}
+
+// Recv decodes the next response into v. io.EOF is returned once the
+// remote handler has returned without an error.
+func (s *Stream) Recv(v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return s.err
+	}
+
+	f, err := s.call.next(s.ctx, s.endpoint.closed)
+	switch {
+	case err != nil:
+		return s.finish(s.endpoint.callError(s.call, err))
+	case f.typ == frameTypeStreamItem:
+		return s.endpoint.codec.Unmarshal(f.payload, v)
+	case f.typ == frameTypeStreamEnd:
+		return s.finish(io.EOF)
+	case f.typ == frameTypeError:
+		return s.finish(&RemoteError{Message: string(f.payload)})
+	default:
+		return s.finish(errUnexpectedFrame)
+	}
+}
+
+// Close stops receiving responses and cancels the call on the remote
+// Endpoint if it is still running
+func (s *Stream) Close() error {
+	// Unblocks a pending Recv, which then cancels the call itself
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return nil
+	}
+	s.finish(ErrClosed) //nolint:errcheck
+
+	if s.endpoint.closeErr() != nil {
+		return nil
+	}
+	return s.endpoint.writeFrame(&frame{typ: frameTypeCancel, callID: s.call.id})
+}
+
+// finish requires the caller holds the lock
+func (s *Stream) finish(err error) error {
+	s.done = true
+	s.err = err
+	s.cancel()
+	s.endpoint.finishCall(s.call)
+	return err
+}
+
+// call is the client side of an in-flight call
+type call struct {
+	id     uint32
+	frames chan frame
+
+	// cancelled is the Done channel of the context of the call
+	cancelled <-chan struct{}
+
+	// finished is closed once the call is removed from the Endpoint
+	finished chan struct{}
+}
+
+// push queues f. It blocks while the queue is full, which stops the read
+// loop and pushes back on the remote until the call is read from or ends.
+func (c *call) push(f frame, closed <-chan struct{}) {
+	select {
+	case c.frames <- f:
+	case <-c.cancelled:
+	case <-c.finished:
+	case <-closed:
+	}
+}
+
+func (c *call) next(ctx context.Context, closed <-chan struct{}) (frame, error) {
+	// Frames that already arrived win over cancellation
+	select {
+	case f := <-c.frames:
+		return f, nil
+	default:
+	}
+
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-ctx.Done():
+		return frame{}, ctx.Err()
+	case <-closed:
+		return frame{}, ErrClosed
+	}
+}
@@ -0,0 +1,423 @@
+package dcrpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// pipeConn is an in-memory MessageConn
+type pipeConn struct {
+	in, out   chan []byte
+	closed    chan struct{}
+	closeOnce *sync.Once
+}
+
+func newPipe() (*pipeConn, *pipeConn) {
+	a, b := make(chan []byte, 16), make(chan []byte, 16)
+	closed := make(chan struct{})
+	closeOnce := &sync.Once{}
+
+	return &pipeConn{in: a, out: b, closed: closed, closeOnce: closeOnce},
+		&pipeConn{in: b, out: a, closed: closed, closeOnce: closeOnce}
+}
+
+func (p *pipeConn) ReadMessage() ([]byte, error) {
+	select {
+	case msg := <-p.in:
+		return msg, nil
+	case <-p.closed:
+		return nil, io.EOF
+	}
+}
+
+func (p *pipeConn) WriteMessage(msg []byte) error {
+	select {
+	case p.out <- msg:
+		return nil
+	case <-p.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+func (p *pipeConn) Close() error {
+	p.closeOnce.Do(func() { close(p.closed) })
+	return nil
+}
+
+type echoRequest struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+type echoResponse struct {
+	Text string `json:"text"`
+}
+
+func newEndpointPair(config Config) (*Endpoint, *Endpoint) {
+	connA, connB := newPipe()
+	return NewEndpoint(connA, config), NewEndpoint(connB, config)
+}
+
+func registerEchoHandlers(e *Endpoint, blocked chan struct{}) {
+	e.Handle("echo", func(ctx context.Context, req *Request) (interface{}, error) {
+		r := echoRequest{}
+		if err := req.Decode(&r); err != nil {
+			return nil, err
+		}
+		return echoResponse{Text: r.Text}, nil
+	})
+
+	e.Handle("fail", func(ctx context.Context, req *Request) (interface{}, error) {
+		return nil, errors.New("handler failed") //nolint:goerr113
+	})
+
+	e.Handle("block", func(ctx context.Context, req *Request) (interface{}, error) {
+		<-ctx.Done()
+		close(blocked)
+		return nil, ctx.Err()
+	})
+
+	e.HandleStream("repeat", func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		r := echoRequest{}
+		if err := req.Decode(&r); err != nil {
+			return err
+		}
+
+		for i := 0; i < r.Count; i++ {
+			if err := send(echoResponse{Text: r.Text}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestEndpoint(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	client, server := newEndpointPair(Config{})
+	blocked := make(chan struct{})
+	registerEchoHandlers(server, blocked)
+
+	t.Run("Unary", func(t *testing.T) {
+		resp := echoResponse{}
+		assert.NoError(t, client.Call(context.Background(), "echo", echoRequest{Text: "hello"}, &resp))
+		assert.Equal(t, "hello", resp.Text)
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			text := strings.Repeat("a", i)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp := echoResponse{}
+				assert.NoError(t, client.Call(context.Background(), "echo", echoRequest{Text: text}, &resp))
+				assert.Equal(t, text, resp.Text)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("RemoteError", func(t *testing.T) {
+		var remoteErr *RemoteError
+		err := client.Call(context.Background(), "fail", nil, nil)
+		assert.True(t, errors.As(err, &remoteErr))
+		assert.Equal(t, "handler failed", remoteErr.Message)
+
+		err = client.Call(context.Background(), "missing", nil, nil)
+		assert.True(t, errors.As(err, &remoteErr))
+		assert.Equal(t, unknownMethodMessage, remoteErr.Message)
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		stream, err := client.CallStream(context.Background(), "repeat", echoRequest{Text: "hi", Count: 3})
+		assert.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			resp := echoResponse{}
+			assert.NoError(t, stream.Recv(&resp))
+			assert.Equal(t, "hi", resp.Text)
+		}
+		assert.Equal(t, io.EOF, stream.Recv(nil))
+		assert.NoError(t, stream.Close())
+	})
+
+	t.Run("Timeout cancels remote handler", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := client.Call(ctx, "block", nil, nil)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		<-blocked
+	})
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+}
+
+func TestEndpoint_CallTimeout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	client, server := newEndpointPair(Config{CallTimeout: 50 * time.Millisecond})
+	blocked := make(chan struct{})
+	registerEchoHandlers(server, blocked)
+
+	err := client.Call(context.Background(), "block", nil, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	<-blocked
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+}
+
+func TestEndpoint_Close(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	client, server := newEndpointPair(Config{})
+	blocked := make(chan struct{})
+	registerEchoHandlers(server, blocked)
+
+	callErr := make(chan error)
+	go func() {
+		callErr <- client.Call(context.Background(), "block", nil, nil)
+	}()
+
+	// Closing the server cancels its handlers and fails pending calls
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, server.Close())
+	<-blocked
+	assert.True(t, errors.Is(<-callErr, ErrClosed))
+
+	assert.True(t, errors.Is(client.Call(context.Background(), "echo", nil, nil), ErrClosed))
+	assert.NoError(t, client.Close())
+}
+
+func TestEndpoint_StreamClose(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	client, server := newEndpointPair(Config{})
+
+	handlerDone := make(chan error)
+	server.HandleStream("forever", func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		for {
+			if err := send(echoResponse{Text: "tick"}); err != nil {
+				handlerDone <- err
+				return err
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	stream, err := client.CallStream(context.Background(), "forever", nil)
+	assert.NoError(t, err)
+
+	resp := echoResponse{}
+	assert.NoError(t, stream.Recv(&resp))
+	assert.NoError(t, stream.Close())
+	assert.True(t, errors.Is(<-handlerDone, context.Canceled))
+	assert.True(t, errors.Is(stream.Recv(&resp), ErrClosed))
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+}
+
+// Assert that a Stream that isn't read stops the Endpoint from reading
+// instead of queueing every response
+func TestEndpoint_StreamBackpressure(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	connA, connB := newPipe()
+	client := NewEndpoint(connA, Config{StreamBufferSize: 4})
+	server := NewEndpoint(connB, Config{})
+
+	const count = 200
+	var sent uint32
+	server.HandleStream("count", func(ctx context.Context, req *Request, send func(interface{}) error) error {
+		for i := 0; i < count; i++ {
+			if err := send(i); err != nil {
+				return err
+			}
+			atomic.AddUint32(&sent, 1)
+		}
+		return nil
+	})
+
+	stream, err := client.CallStream(context.Background(), "count", nil)
+	assert.NoError(t, err)
+
+	// The queue of the call and the buffers of the pipe fill up, then the
+	// handler blocks in send
+	time.Sleep(100 * time.Millisecond)
+	assert.Less(t, atomic.LoadUint32(&sent), uint32(4+2*16))
+
+	for i := 0; i < count; i++ {
+		var v int
+		assert.NoError(t, stream.Recv(&v))
+		assert.Equal(t, i, v)
+	}
+	assert.Equal(t, io.EOF, stream.Recv(nil))
+	assert.NoError(t, stream.Close())
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+}
+
+// Assert that a request reusing the ID of a running call is rejected and
+// doesn't replace the running call
+func TestEndpoint_DuplicateCallID(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	conn, remote := newPipe()
+	server := NewEndpoint(conn, Config{})
+
+	release := make(chan struct{})
+	server.Handle("wait", func(ctx context.Context, req *Request) (interface{}, error) {
+		<-release
+		return "done", nil
+	})
+
+	readFrame := func() frame {
+		raw, err := remote.ReadMessage()
+		assert.NoError(t, err)
+		f := frame{}
+		assert.NoError(t, f.unmarshal(raw))
+		return f
+	}
+
+	request, err := (&frame{typ: frameTypeRequest, callID: 7, method: "wait", payload: []byte("null")}).marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, remote.WriteMessage(request))
+	assert.NoError(t, remote.WriteMessage(request))
+
+	f := readFrame()
+	assert.Equal(t, frameTypeError, f.typ)
+	assert.Equal(t, uint32(7), f.callID)
+	assert.Equal(t, duplicateCallIDMessage, string(f.payload))
+
+	// The first call is still served and can be cancelled
+	server.mu.Lock()
+	assert.Len(t, server.serving, 1)
+	server.mu.Unlock()
+
+	close(release)
+	f = readFrame()
+	assert.Equal(t, frameTypeResponse, f.typ)
+	assert.Equal(t, uint32(7), f.callID)
+
+	assert.NoError(t, server.Close())
+}
+
+func TestEndpoint_PeerConnection(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	answerPC, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	serverReady := make(chan *Endpoint, 1)
+	answerPC.OnDataChannel(func(d *webrtc.DataChannel) {
+		server := NewEndpoint(NewDataChannelConn(d), Config{Codec: BinaryCodec{}})
+		server.Handle("reverse", func(ctx context.Context, req *Request) (interface{}, error) {
+			var in []byte
+			if err := req.Decode(&in); err != nil {
+				return nil, err
+			}
+
+			out := make([]byte, len(in))
+			for i := range in {
+				out[len(in)-1-i] = in[i]
+			}
+			return out, nil
+		})
+		serverReady <- server
+	})
+
+	dc, err := offerPC.CreateDataChannel("rpc", nil)
+	assert.NoError(t, err)
+
+	client := NewEndpoint(NewDataChannelConn(dc), Config{Codec: BinaryCodec{}})
+	opened := make(chan struct{})
+	dc.OnOpen(func() { close(opened) })
+
+	assert.NoError(t, signalPair(offerPC, answerPC))
+	<-opened
+	server := <-serverReady
+
+	var resp []byte
+	assert.NoError(t, client.Call(context.Background(), "reverse", []byte("pion"), &resp))
+	assert.Equal(t, []byte("noip"), resp)
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func signalPair(offerPC, answerPC *webrtc.PeerConnection) error {
+	offer, err := offerPC.CreateOffer(nil)
+	if err != nil {
+		return err
+	}
+	offerGatheringComplete := webrtc.GatheringCompletePromise(offerPC)
+	if err = offerPC.SetLocalDescription(offer); err != nil {
+		return err
+	}
+	<-offerGatheringComplete
+
+	if err = answerPC.SetRemoteDescription(*offerPC.LocalDescription()); err != nil {
+		return err
+	}
+
+	answer, err := answerPC.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	answerGatheringComplete := webrtc.GatheringCompletePromise(answerPC)
+	if err = answerPC.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	<-answerGatheringComplete
+
+	return offerPC.SetRemoteDescription(*answerPC.LocalDescription())
+}
@@ -0,0 +1,100 @@
+package dcrpc
+
+import (
+	"encoding/binary"
+)
+
+type frameType byte
+
+const (
+	// frameTypeRequest starts a call, carries the method name and the request
+	frameTypeRequest frameType = iota + 1
+	// frameTypeResponse completes a unary call with its response
+	frameTypeResponse
+	// frameTypeStreamItem carries one response of a streaming call
+	frameTypeStreamItem
+	// frameTypeStreamEnd completes a streaming call
+	frameTypeStreamEnd
+	// frameTypeError completes a call with the error returned by the handler
+	frameTypeError
+	// frameTypeCancel asks the remote to abandon a call
+	frameTypeCancel
+)
+
+const (
+	frameHeaderLength    = 5
+	methodLengthFieldLen = 2
+	maxMethodLength      = 65535
+)
+
+// frame is a single DataChannel message
+//
+//	0                   1                   2                   3
+//	0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|     Type      |                    Call ID                    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|               |  Method Length (requests only)  |   Method    |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+//	|                        Payload ...                            |
+//	+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+type frame struct {
+	typ     frameType
+	callID  uint32
+	method  string
+	payload []byte
+}
+
+func (f *frame) marshal() ([]byte, error) {
+	size := frameHeaderLength + len(f.payload)
+	if f.typ == frameTypeRequest {
+		if len(f.method) > maxMethodLength {
+			return nil, errMethodTooLong
+		}
+		size += methodLengthFieldLen + len(f.method)
+	}
+
+	raw := make([]byte, size)
+	raw[0] = byte(f.typ)
+	binary.BigEndian.PutUint32(raw[1:], f.callID)
+
+	offset := frameHeaderLength
+	if f.typ == frameTypeRequest {
+		binary.BigEndian.PutUint16(raw[offset:], uint16(len(f.method)))
+		offset += methodLengthFieldLen
+		offset += copy(raw[offset:], f.method)
+	}
+	copy(raw[offset:], f.payload)
+
+	return raw, nil
+}
+
+func (f *frame) unmarshal(raw []byte) error {
+	if len(raw) < frameHeaderLength {
+		return errFrameTooShort
+	}
+
+	f.typ = frameType(raw[0])
+	if f.typ < frameTypeRequest || f.typ > frameTypeCancel {
+		return errUnknownFrameType
+	}
+	f.callID = binary.BigEndian.Uint32(raw[1:])
+
+	offset := frameHeaderLength
+	if f.typ == frameTypeRequest {
+		if len(raw) < offset+methodLengthFieldLen {
+			return errFrameTooShort
+		}
+		methodLength := int(binary.BigEndian.Uint16(raw[offset:]))
+		offset += methodLengthFieldLen
+
+		if len(raw) < offset+methodLength {
+			return errFrameTooShort
+		}
+		f.method = string(raw[offset : offset+methodLength])
+		offset += methodLength
+	}
+
+	f.payload = append([]byte{}, raw[offset:]...)
+	return nil
+}
@@ -0,0 +1,41 @@
+package dcrpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame_RoundTrip(t *testing.T) {
+	for _, f := range []frame{
+		{typ: frameTypeRequest, callID: 1, method: "echo", payload: []byte{0x01, 0x02}},
+		{typ: frameTypeRequest, callID: 2, method: "", payload: []byte{}},
+		{typ: frameTypeResponse, callID: 0xFFFFFFFF, payload: []byte("response")},
+		{typ: frameTypeStreamEnd, callID: 3, payload: []byte{}},
+		{typ: frameTypeCancel, callID: 4, payload: []byte{}},
+	} {
+		raw, err := f.marshal()
+		assert.NoError(t, err)
+
+		parsed := frame{}
+		assert.NoError(t, parsed.unmarshal(raw))
+		assert.Equal(t, f, parsed)
+	}
+}
+
+func TestFrame_Unmarshal_Invalid(t *testing.T) {
+	for _, test := range []struct {
+		raw []byte
+		err error
+	}{
+		{[]byte{0x01, 0x00}, errFrameTooShort},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x01}, errUnknownFrameType},
+		{[]byte{0x07, 0x00, 0x00, 0x00, 0x01}, errUnknownFrameType},
+		{[]byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x00}, errFrameTooShort},
+		{[]byte{0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x05, 'e', 'c'}, errFrameTooShort},
+	} {
+		f := frame{}
+		assert.True(t, errors.Is(f.unmarshal(test.raw), test.err))
+	}
+}
@@ -0,0 +1,327 @@
+// Package sframe implements SFrame end-to-end encryption of media frames as
+// described in RFC 9605. A Context encrypts frames before packetization and
+// decrypts them after depacketization, so intermediaries like SFUs can forward
+// the RTP packets without being able to read the media. Pass Encrypt to
+// webrtc.RTPSender.SetFrameTransform and Decrypt to
+// webrtc.RTPReceiver.SetFrameTransform.
+package sframe
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+	"sync"
+)
+
+// CipherSuite identifies the AEAD and hash used to protect frames
+type CipherSuite uint16
+
+// Supported cipher suites, see RFC 9605 Section 4.5
+const (
+	CipherSuiteAES128GCMSHA256 CipherSuite = 0x0004
+	CipherSuiteAES256GCMSHA512 CipherSuite = 0x0005
+)
+
+const (
+	nonceSize     = 12
+	tagSize       = 16
+	maxHeaderSize = 1 + 8 + 8
+)
+
+var (
+	errUnsupportedCipherSuite = errors.New("sframe: unsupported cipher suite")
+	errUnknownKeyID           = errors.New("sframe: unknown key id")
+	errKeyIDInUse             = errors.New("sframe: key id already in use with a different key")
+	errNoSendKey              = errors.New("sframe: no send key set")
+	errCounterExhausted       = errors.New("sframe: counter exhausted, a new key is required")
+	errShortFrame             = errors.New("sframe: frame too short")
+	errDecryptionFailed       = errors.New("sframe: decryption failed")
+)
+
+func (s CipherSuite) params() (newHash func() hash.Hash, keySize int, err error) {
+	switch s {
+	case CipherSuiteAES128GCMSHA256:
+		return sha256.New, 16, nil
+	case CipherSuiteAES256GCMSHA512:
+		return sha512.New, 32, nil
+	default:
+		return nil, 0, errUnsupportedCipherSuite
+	}
+}
+
+type key struct {
+	baseKey []byte
+	aead    cipher.AEAD
+	salt    []byte
+	counter uint64
+}
+
+// Context holds the keys of one SFrame session. It is safe for concurrent use.
+type Context struct {
+	mu      sync.Mutex
+	suite   CipherSuite
+	newHash func() hash.Hash
+	keySize int
+	keys    map[uint64]*key
+
+	sendKeyID  uint64
+	hasSendKey bool
+}
+
+// NewContext creates a Context using the given cipher suite
+func NewContext(suite CipherSuite) (*Context, error) {
+	newHash, keySize, err := suite.params()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Context{
+		suite:   suite,
+		newHash: newHash,
+		keySize: keySize,
+		keys:    map[uint64]*key{},
+	}, nil
+}
+
+// AddKey derives the encryption key and salt for keyID from baseKey.
+// Adding an existing keyID with the same baseKey keeps its counter, a
+// different baseKey is rejected since reusing a keyID would reuse nonces.
+// Call RemoveKey and pick a new keyID to change keys.
+func (c *Context) AddKey(keyID uint64, baseKey []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.addKey(keyID, baseKey)
+}
+
+func (c *Context) addKey(keyID uint64, baseKey []byte) error {
+	if k, ok := c.keys[keyID]; ok {
+		if !bytes.Equal(k.baseKey, baseKey) {
+			return errKeyIDInUse
+		}
+		return nil
+	}
+
+	// RFC 9605 Section 4.4.2
+	label := make([]byte, 8+2)
+	binary.BigEndian.PutUint64(label, keyID)
+	binary.BigEndian.PutUint16(label[8:], uint16(c.suite))
+
+	secret := hkdfExtract(c.newHash, nil, baseKey)
+	encryptionKey := hkdfExpand(c.newHash, secret, append([]byte("SFrame 1.0 Secret key "), label...), c.keySize)
+	salt := hkdfExpand(c.newHash, secret, append([]byte("SFrame 1.0 Secret salt "), label...), nonceSize)
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	c.keys[keyID] = &key{
+		baseKey: append([]byte{}, baseKey...),
+		aead:    aead,
+		salt:    salt,
+	}
+	return nil
+}
+
+// RemoveKey forgets keyID. Frames encrypted with it can't be decrypted anymore.
+func (c *Context) RemoveKey(keyID uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.keys, keyID)
+	if c.sendKeyID == keyID {
+		c.hasSendKey = false
+	}
+}
+
+// SetSendKey selects the key used by Encrypt
+func (c *Context) SetSendKey(keyID uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.keys[keyID]; !ok {
+		return errUnknownKeyID
+	}
+	c.sendKeyID = keyID
+	c.hasSendKey = true
+	return nil
+}
+
+// Ratchet derives the base key of newKeyID from the base key of keyID, so
+// peers can move to a fresh key without exchanging new key material. RFC 9605
+// leaves key management to the application; the next base key is
+// HKDF-Expand(HKDF-Extract("", base_key), "SFrame 1.0 Ratchet", Nh).
+// The old key is kept so frames in flight still decrypt, call RemoveKey to
+// drop it.
+func (c *Context) Ratchet(keyID, newKeyID uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k, ok := c.keys[keyID]
+	if !ok {
+		return errUnknownKeyID
+	}
+
+	secret := hkdfExtract(c.newHash, nil, k.baseKey)
+	nextBaseKey := hkdfExpand(c.newHash, secret, []byte("SFrame 1.0 Ratchet"), c.newHash().Size())
+	return c.addKey(newKeyID, nextBaseKey)
+}
+
+// Encrypt protects frame with the send key and returns the SFrame ciphertext
+func (c *Context) Encrypt(frame []byte) ([]byte, error) {
+	c.mu.Lock()
+	if !c.hasSendKey {
+		c.mu.Unlock()
+		return nil, errNoSendKey
+	}
+	keyID := c.sendKeyID
+	k := c.keys[keyID]
+	if k.counter == math.MaxUint64 {
+		c.mu.Unlock()
+		return nil, errCounterExhausted
+	}
+	counter := k.counter
+	k.counter++
+	c.mu.Unlock()
+
+	out := make([]byte, 0, maxHeaderSize+len(frame)+tagSize)
+	out = appendHeader(out, keyID, counter)
+	header := out[:len(out):len(out)]
+
+	return k.aead.Seal(out, nonce(k.salt, counter), frame, header), nil
+}
+
+// Decrypt verifies and decrypts an SFrame ciphertext created by Encrypt
+func (c *Context) Decrypt(frame []byte) ([]byte, error) {
+	keyID, counter, headerSize, err := parseHeader(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	k, ok := c.keys[keyID]
+	c.mu.Unlock()
+	if !ok {
+		return nil, errUnknownKeyID
+	}
+
+	if len(frame) < headerSize+tagSize {
+		return nil, errShortFrame
+	}
+
+	plaintext, err := k.aead.Open(nil, nonce(k.salt, counter), frame[headerSize:], frame[:headerSize])
+	if err != nil {
+		return nil, errDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+func nonce(salt []byte, counter uint64) []byte {
+	n := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(n[nonceSize-8:], counter)
+	for i := range n {
+		n[i] ^= salt[i]
+	}
+	return n
+}
+
+// appendHeader encodes the SFrame header, RFC 9605 Section 4.3.
+// Values below 8 are stored in the config byte, larger ones use the
+// minimal number of big-endian bytes.
+func appendHeader(b []byte, keyID, counter uint64) []byte {
+	config := byte(0)
+	var keyIDBytes, counterBytes []byte
+
+	if keyID < 8 {
+		config |= byte(keyID) << 4
+	} else {
+		keyIDBytes = minimalBigEndian(keyID)
+		config |= 0x80 | byte(len(keyIDBytes)-1)<<4
+	}
+
+	if counter < 8 {
+		config |= byte(counter)
+	} else {
+		counterBytes = minimalBigEndian(counter)
+		config |= 0x08 | byte(len(counterBytes)-1)
+	}
+
+	b = append(b, config)
+	b = append(b, keyIDBytes...)
+	return append(b, counterBytes...)
+}
+
+func parseHeader(frame []byte) (keyID, counter uint64, headerSize int, err error) {
+	if len(frame) < 1 {
+		return 0, 0, 0, errShortFrame
+	}
+
+	config := frame[0]
+	headerSize = 1
+
+	readValue := func(extended bool, value byte) (uint64, error) {
+		if !extended {
+			return uint64(value), nil
+		}
+
+		length := int(value) + 1
+		if len(frame) < headerSize+length {
+			return 0, errShortFrame
+		}
+
+		var v uint64
+		for _, b := range frame[headerSize : headerSize+length] {
+			v = v<<8 | uint64(b)
+		}
+		headerSize += length
+		return v, nil
+	}
+
+	if keyID, err = readValue(config&0x80 != 0, (config>>4)&0x07); err != nil {
+		return 0, 0, 0, err
+	}
+	if counter, err = readValue(config&0x08 != 0, config&0x07); err != nil {
+		return 0, 0, 0, err
+	}
+	return keyID, counter, headerSize, nil
+}
+
+func minimalBigEndian(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	for len(b) > 1 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+func hkdfExtract(newHash func() hash.Hash, salt, ikm []byte) []byte {
+	mac := hmac.New(newHash, salt)
+	mac.Write(ikm) //nolint:errcheck
+	return mac.Sum(nil)
+}
+
+func hkdfExpand(newHash func() hash.Hash, prk, info []byte, length int) []byte {
+	var out, previous []byte
+	for i := byte(1); len(out) < length; i++ {
+		mac := hmac.New(newHash, prk)
+		mac.Write(previous)  //nolint:errcheck
+		mac.Write(info)      //nolint:errcheck
+		mac.Write([]byte{i}) //nolint:errcheck
+		previous = mac.Sum(nil)
+		out = append(out, previous...)
+	}
+	return out[:length]
+}
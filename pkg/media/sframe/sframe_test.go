@@ -0,0 +1,188 @@
+package sframe
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader(t *testing.T) {
+	for _, test := range []struct {
+		keyID, counter uint64
+		header         []byte
+	}{
+		{0, 0, []byte{0x00}},
+		{7, 7, []byte{0x77}},
+		{8, 0, []byte{0x80, 0x08}},
+		{0, 8, []byte{0x08, 0x08}},
+		{0x1234, 0x123456, []byte{0x9a, 0x12, 0x34, 0x12, 0x34, 0x56}},
+		{^uint64(0), ^uint64(0), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	} {
+		header := appendHeader(nil, test.keyID, test.counter)
+		assert.Equal(t, test.header, header)
+
+		keyID, counter, headerSize, err := parseHeader(header)
+		assert.NoError(t, err)
+		assert.Equal(t, test.keyID, keyID)
+		assert.Equal(t, test.counter, counter)
+		assert.Equal(t, len(header), headerSize)
+	}
+
+	_, _, _, err := parseHeader([]byte{0x9a, 0x12})
+	assert.Equal(t, errShortFrame, err)
+}
+
+func TestContext(t *testing.T) {
+	baseKey := []byte("0123456789abcdef")
+	frame := []byte("an encoded media frame")
+
+	for _, suite := range []CipherSuite{CipherSuiteAES128GCMSHA256, CipherSuiteAES256GCMSHA512} {
+		sender, err := NewContext(suite)
+		assert.NoError(t, err)
+		receiver, err := NewContext(suite)
+		assert.NoError(t, err)
+
+		_, err = sender.Encrypt(frame)
+		assert.Equal(t, errNoSendKey, err)
+
+		assert.NoError(t, sender.AddKey(42, baseKey))
+		assert.NoError(t, sender.SetSendKey(42))
+
+		encrypted, err := sender.Encrypt(frame)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(encrypted), string(frame))
+
+		_, err = receiver.Decrypt(encrypted)
+		assert.Equal(t, errUnknownKeyID, err)
+
+		assert.NoError(t, receiver.AddKey(42, baseKey))
+		decrypted, err := receiver.Decrypt(encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, frame, decrypted)
+
+		// Every frame uses a new counter
+		second, err := sender.Encrypt(frame)
+		assert.NoError(t, err)
+		assert.NotEqual(t, encrypted, second)
+
+		// The header is authenticated
+		second[0] ^= 0x01
+		_, err = receiver.Decrypt(second)
+		assert.Error(t, err)
+	}
+
+	_, err := NewContext(CipherSuite(0x0001))
+	assert.Equal(t, errUnsupportedCipherSuite, err)
+}
+
+func TestContext_Ratchet(t *testing.T) {
+	sender, err := NewContext(CipherSuiteAES128GCMSHA256)
+	assert.NoError(t, err)
+	receiver, err := NewContext(CipherSuiteAES128GCMSHA256)
+	assert.NoError(t, err)
+
+	baseKey := []byte("0123456789abcdef")
+	assert.NoError(t, sender.AddKey(1, baseKey))
+	assert.NoError(t, receiver.AddKey(1, baseKey))
+	assert.Equal(t, errUnknownKeyID, sender.Ratchet(3, 4))
+
+	assert.NoError(t, sender.SetSendKey(1))
+	beforeRatchet, err := sender.Encrypt([]byte{0x01})
+	assert.NoError(t, err)
+
+	assert.NoError(t, sender.Ratchet(1, 2))
+	assert.NoError(t, sender.SetSendKey(2))
+	afterRatchet, err := sender.Encrypt([]byte{0x02})
+	assert.NoError(t, err)
+
+	// The receiver ratchets on its own and can still read frames in flight
+	assert.NoError(t, receiver.Ratchet(1, 2))
+	decrypted, err := receiver.Decrypt(afterRatchet)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x02}, decrypted)
+
+	decrypted, err = receiver.Decrypt(beforeRatchet)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, decrypted)
+
+	receiver.RemoveKey(1)
+	_, err = receiver.Decrypt(beforeRatchet)
+	assert.Equal(t, errUnknownKeyID, err)
+}
+
+func TestContext_AddExistingKey(t *testing.T) {
+	c, err := NewContext(CipherSuiteAES128GCMSHA256)
+	assert.NoError(t, err)
+
+	baseKey := []byte("0123456789abcdef")
+	assert.NoError(t, c.AddKey(1, baseKey))
+	assert.NoError(t, c.SetSendKey(1))
+	_, err = c.Encrypt([]byte{0x01})
+	assert.NoError(t, err)
+
+	// Re-adding the same key must not restart the counter and reuse a nonce
+	assert.NoError(t, c.AddKey(1, baseKey))
+	frame, err := c.Encrypt([]byte{0x02})
+	assert.NoError(t, err)
+	_, counter, _, err := parseHeader(frame)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), counter)
+
+	assert.Equal(t, errKeyIDInUse, c.AddKey(1, []byte("fedcba9876543210")))
+	assert.Equal(t, errKeyIDInUse, c.Ratchet(1, 1))
+}
+
+// The expected values were computed independently of this package, with
+// HKDF from the Python standard library and AES-GCM from OpenSSL, following
+// the key schedule and encryption of RFC 9605 Section 4.4
+func TestContext_KnownAnswer(t *testing.T) {
+	mustDecode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		return b
+	}
+
+	baseKey := mustDecode("000102030405060708090a0b0c0d0e0f")
+	plaintext := []byte("draft-ietf-sframe-enc")
+	const keyID, counter = 0x123, 0x4567
+
+	for _, test := range []struct {
+		suite         CipherSuite
+		salt          string
+		ciphertext    string
+		ratchetedBase string
+	}{
+		{
+			suite:         CipherSuiteAES128GCMSHA256,
+			salt:          "75234edefe07819026751816",
+			ciphertext:    "9901234567b7412c2513a1b66dbb48841bbaf17f598751176ad8df84a3549f4741b50b16fea736056ced",
+			ratchetedBase: "fb75d8d5782da6c6cbf18ac43eca5da9e47f7e6ac7926a78e486226bd2af0f87",
+		},
+		{
+			suite:      CipherSuiteAES256GCMSHA512,
+			salt:       "84991c167b8cd23c93708ec7",
+			ciphertext: "990123456794f509d36e9beacb0e261d99c7d1e972f1fed787d4df91e94031127808638a75f6de5495df",
+			ratchetedBase: "895fe5603750295ccbe0d5ed9745617b46e9cf9b428179b8f29f3147492bb08f" +
+				"aa190560720ee0e4570760b64e7d5931120c391b7c7becc429ea35a9d07475aa",
+		},
+	} {
+		c, err := NewContext(test.suite)
+		assert.NoError(t, err)
+		assert.NoError(t, c.AddKey(keyID, baseKey))
+		assert.NoError(t, c.SetSendKey(keyID))
+		assert.Equal(t, mustDecode(test.salt), c.keys[keyID].salt)
+
+		c.keys[keyID].counter = counter
+		encrypted, err := c.Encrypt(plaintext)
+		assert.NoError(t, err)
+		assert.Equal(t, mustDecode(test.ciphertext), encrypted)
+
+		decrypted, err := c.Decrypt(mustDecode(test.ciphertext))
+		assert.NoError(t, err)
+		assert.Equal(t, plaintext, decrypted)
+
+		assert.NoError(t, c.Ratchet(keyID, keyID+1))
+		assert.Equal(t, mustDecode(test.ratchetedBase), c.keys[keyID+1].baseKey)
+	}
+}
@@ -42,11 +42,16 @@ type RTPReceiver struct {
 
 	tracks []trackStreams
 
+	// streamsGeneration is incremented when a DTLS restart rebinds the streams
+	streamsGeneration uint64
+
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
 	tr *RTPTransceiver
 
+	frameTransform FrameTransform
+
 	// A reference to the associated api object
 	api *API
 }
@@ -123,6 +128,23 @@ func (r *RTPReceiver) Tracks() []*TrackRemote {
 	return tracks
 }
 
+// SetFrameTransform sets a transform that is run on every frame after it is
+// depacketized, e.g. sframe.Context.Decrypt. Reading the tracks of the
+// RTPReceiver then returns the transformed frames packetized for the codec of
+// the track. Frames the transform fails on are dropped. It can be set in
+// OnTrack, before the track is read.
+func (r *RTPReceiver) SetFrameTransform(transform FrameTransform) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frameTransform = transform
+}
+
+func (r *RTPReceiver) getFrameTransform() FrameTransform {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.frameTransform
+}
+
 // configureReceive initialize the track
 func (r *RTPReceiver) configureReceive(parameters RTPReceiveParameters) {
 	r.mu.Lock()
@@ -210,7 +232,11 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 func (r *RTPReceiver) Read(b []byte) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.received:
-		return r.tracks[0].rtcpInterceptor.Read(b, a)
+		return r.readRebound(func() (*trackStreams, error) {
+			return &r.tracks[0], nil
+		}, func(t trackStreams) (int, interceptor.Attributes, error) {
+			return t.rtcpInterceptor.Read(b, a)
+		})
 	case <-r.closed:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -220,12 +246,16 @@ func (r *RTPReceiver) Read(b []byte) (n int, a interceptor.Attributes, err error
 func (r *RTPReceiver) ReadSimulcast(b []byte, rid string) (n int, a interceptor.Attributes, err error) {
 	select {
 	case <-r.received:
-		for _, t := range r.tracks {
-			if t.track != nil && t.track.rid == rid {
-				return t.rtcpInterceptor.Read(b, a)
+		return r.readRebound(func() (*trackStreams, error) {
+			for i := range r.tracks {
+				if r.tracks[i].track != nil && r.tracks[i].track.rid == rid {
+					return &r.tracks[i], nil
+				}
 			}
-		}
-		return 0, nil, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
+			return nil, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
+		}, func(t trackStreams) (int, interceptor.Attributes, error) {
+			return t.rtcpInterceptor.Read(b, a)
+		})
 	case <-r.closed:
 		return 0, nil, io.ErrClosedPipe
 	}
@@ -331,11 +361,100 @@ func (r *RTPReceiver) streamsForTrack(t *TrackRemote) *trackStreams {
 // readRTP should only be called by a track, this only exists so we can keep state in one place
 func (r *RTPReceiver) readRTP(b []byte, reader *TrackRemote) (n int, a interceptor.Attributes, err error) {
 	<-r.received
-	if t := r.streamsForTrack(reader); t != nil {
+	return r.readRebound(func() (*trackStreams, error) {
+		if t := r.streamsForTrack(reader); t != nil {
+			return t, nil
+		}
+		return nil, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
+	}, func(t trackStreams) (int, interceptor.Attributes, error) {
 		return t.rtpInterceptor.Read(b, a)
+	})
+}
+
+// readRebound calls read with the streams returned by find. If a DTLS restart
+// rebound the streams while read was blocked, read is retried with the new ones.
+func (r *RTPReceiver) readRebound(find func() (*trackStreams, error), read func(trackStreams) (int, interceptor.Attributes, error)) (int, interceptor.Attributes, error) {
+	for {
+		r.mu.RLock()
+		t, err := find()
+		var streams trackStreams
+		if t != nil {
+			streams = *t
+		}
+		generation := r.streamsGeneration
+		r.mu.RUnlock()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		n, a, err := read(streams)
+		if err == nil {
+			return n, a, nil
+		}
+
+		r.mu.RLock()
+		rebound := r.streamsGeneration != generation
+		r.mu.RUnlock()
+		if !rebound {
+			return n, a, err
+		}
+	}
+}
+
+// rebindStreams opens the streams of all tracks on the SRTP sessions of the
+// restarted DTLSTransport. The previous streams are closed afterwards, so
+// blocked reads return and are retried on the new streams.
+func (r *RTPReceiver) rebindStreams() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return nil
+	default:
 	}
+	if !r.haveReceived() {
+		return nil
+	}
+
+	errs := []error{}
+	previous := []io.Closer{}
+	for i := range r.tracks {
+		t := &r.tracks[i]
+
+		if t.streamInfo != nil {
+			r.api.interceptor.UnbindRemoteStream(t.streamInfo)
+			rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, err := r.transport.streamsForSSRC(SSRC(t.streamInfo.SSRC), *t.streamInfo)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
 
-	return 0, nil, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
+			previous = append(previous, t.rtpReadStream, t.rtcpReadStream)
+			t.rtpReadStream, t.rtpInterceptor = rtpReadStream, rtpInterceptor
+			t.rtcpReadStream, t.rtcpInterceptor = rtcpReadStream, rtcpInterceptor
+		}
+
+		if t.repairStreamInfo != nil {
+			r.api.interceptor.UnbindRemoteStream(t.repairStreamInfo)
+			rtpReadStream, rtpInterceptor, rtcpReadStream, rtcpInterceptor, err := r.transport.streamsForSSRC(SSRC(t.repairStreamInfo.SSRC), *t.repairStreamInfo)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			previous = append(previous, t.repairReadStream, t.repairRtcpReadStream)
+			t.repairReadStream, t.repairInterceptor = rtpReadStream, rtpInterceptor
+			t.repairRtcpReadStream, t.repairRtcpInterceptor = rtcpReadStream, rtcpInterceptor
+			go r.readRepairStream(rtpInterceptor)
+		}
+	}
+	r.streamsGeneration++
+
+	for _, stream := range previous {
+		errs = append(errs, stream.Close())
+	}
+	return util.FlattenErrs(errs)
 }
 
 // receiveForRid is the sibling of Receive expect for RIDs instead of SSRCs
@@ -391,17 +510,19 @@ func (r *RTPReceiver) receiveForRtx(ssrc SSRC, rsid string, streamInfo *intercep
 	track.repairRtcpReadStream = rtcpReadStream
 	track.repairRtcpInterceptor = rtcpInterceptor
 
-	go func() {
-		b := make([]byte, r.api.settingEngine.getReceiveMTU())
-		for {
-			if _, _, readErr := track.repairInterceptor.Read(b, nil); readErr != nil {
-				return
-			}
-		}
-	}()
+	go r.readRepairStream(rtpInterceptor)
 	return nil
 }
 
+func (r *RTPReceiver) readRepairStream(repairInterceptor interceptor.RTPReader) {
+	b := make([]byte, r.api.settingEngine.getReceiveMTU())
+	for {
+		if _, _, readErr := repairInterceptor.Read(b, nil); readErr != nil {
+			return
+		}
+	}
+}
+
 // SetReadDeadline sets the max amount of time the RTCP stream will block before returning. 0 is forever.
 func (r *RTPReceiver) SetReadDeadline(t time.Time) error {
 	r.mu.RLock()
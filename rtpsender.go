@@ -50,6 +50,8 @@ type RTPSender struct {
 
 	rtpTransceiver *RTPTransceiver
 
+	frameTransform FrameTransform
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan struct{}
 }
@@ -280,6 +282,22 @@ func (r *RTPSender) ReplaceTrack(track TrackLocal) error {
 	return nil
 }
 
+// SetFrameTransform sets a transform that is run on every frame before it is
+// packetized, e.g. sframe.Context.Encrypt. The frames are assembled from the
+// packets written by the track, so it works with any TrackLocal. It must be set
+// before the RTPSender starts sending, see FrameTransform for how transformed
+// frames are sent. Writes to the track fail with the error of the transform.
+func (r *RTPSender) SetFrameTransform(transform FrameTransform) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hasSent() {
+		return errRTPSenderSendAlreadyCalled
+	}
+	r.frameTransform = transform
+	return nil
+}
+
 // Send Attempts to set the parameters controlling the sending of media.
 func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.mu.Lock()
@@ -322,6 +340,9 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 				return srtpStream.WriteRTP(header, payload)
 			}),
 		)
+		if r.frameTransform != nil {
+			rtpInterceptor = newFrameTransformWriter(r.frameTransform, trackEncoding.track.Kind(), codec.RTPCodecCapability, rtpInterceptor)
+		}
 		writeStream.interceptor.Store(rtpInterceptor)
 	}
 
@@ -358,6 +379,33 @@ func (r *RTPSender) Stop() error {
 	return util.FlattenErrs(errs)
 }
 
+// resetSRTPStreams discards writes while the DTLSTransport restarts
+func (r *RTPSender) resetSRTPStreams() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, trackEncoding := range r.trackEncodings {
+		trackEncoding.srtpStream.reset()
+	}
+}
+
+// rebindSRTPStreams opens the streams on the SRTP sessions of the restarted
+// DTLSTransport
+func (r *RTPSender) rebindSRTPStreams() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.hasStopped() {
+		return nil
+	}
+
+	errs := []error{}
+	for _, trackEncoding := range r.trackEncodings {
+		errs = append(errs, trackEncoding.srtpStream.rebind())
+	}
+	return util.FlattenErrs(errs)
+}
+
 // Read reads incoming RTCP for this RTPSender
 func (r *RTPSender) Read(b []byte) (n int, a interceptor.Attributes, err error) {
 	select {
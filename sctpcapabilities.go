@@ -3,4 +3,8 @@ package webrtc
 // SCTPCapabilities indicates the capabilities of the SCTPTransport.
 type SCTPCapabilities struct {
 	MaxMessageSize uint32 `json:"maxMessageSize"`
+
+	// ZeroChecksum is set when the SCTPTransport accepts packets without a
+	// checksum (RFC 9653), see SettingEngine.EnableSCTPZeroChecksum
+	ZeroChecksum bool `json:"zeroChecksum,omitempty"`
 }
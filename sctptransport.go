@@ -86,6 +86,7 @@ func (r *SCTPTransport) Transport() *DTLSTransport {
 func (r *SCTPTransport) GetCapabilities() SCTPCapabilities {
 	return SCTPCapabilities{
 		MaxMessageSize: 0,
+		ZeroChecksum:   r.api.settingEngine.sctp.enableZeroChecksum,
 	}
 }
 
@@ -106,6 +107,7 @@ func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	sctpAssociation, err := sctp.Client(sctp.Config{
 		NetConn:              dtlsTransport.conn,
 		MaxReceiveBufferSize: r.api.settingEngine.sctp.maxReceiveBufferSize,
+		EnableZeroChecksum:   r.api.settingEngine.sctp.enableZeroChecksum,
 		LoggerFactory:        r.api.settingEngine.LoggerFactory,
 	})
 	if err != nil {
@@ -157,6 +159,28 @@ func (r *SCTPTransport) Stop() error {
 	return nil
 }
 
+// reset prepares the SCTPTransport to be started again over a restarted
+// DTLSTransport. DataChannels of the previous association are dropped, the
+// ones that haven't been opened yet are opened by the next Start.
+func (r *SCTPTransport) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.isStarted = false
+	r.state = SCTPTransportStateConnecting
+
+	dataChannels := []*DataChannel{}
+	for _, dc := range r.dataChannels {
+		dc.mu.Lock()
+		isNil := dc.dataChannel == nil
+		dc.mu.Unlock()
+		if isNil {
+			dataChannels = append(dataChannels, dc)
+		}
+	}
+	r.dataChannels = dataChannels
+}
+
 func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	r.lock.RLock()
 	dataChannels := make([]*datachannel.DataChannel, 0, len(r.dataChannels))
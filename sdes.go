@@ -0,0 +1,222 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/srtp/v2"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
+)
+
+const sdpAttributeCrypto = "crypto"
+
+// sdesCryptoSuite is an RFC 4568 crypto-suite and its SRTP protection profile
+type sdesCryptoSuite struct {
+	name    string
+	profile srtp.ProtectionProfile
+	keyLen  int
+	saltLen int
+}
+
+// sdesCryptoSuites are offered in order of preference. The tag of each
+// offered a=crypto attribute is its index plus one.
+var sdesCryptoSuites = []sdesCryptoSuite{ // nolint:gochecknoglobals
+	{"AEAD_AES_128_GCM", srtp.ProtectionProfileAeadAes128Gcm, 16, 12},
+	{"AES_CM_128_HMAC_SHA1_80", srtp.ProtectionProfileAes128CmHmacSha1_80, 16, 14},
+	{"AES_CM_128_HMAC_SHA1_32", srtp.ProtectionProfileAes128CmHmacSha1_32, 16, 14},
+}
+
+// sdesCrypto is a single a=crypto attribute
+type sdesCrypto struct {
+	tag     int
+	suite   sdesCryptoSuite
+	keySalt []byte
+}
+
+// sameKey reports if both attributes key SRTP the same way, the tag
+// doesn't matter for that
+func (c sdesCrypto) sameKey(other sdesCrypto) bool {
+	return c.suite.name == other.suite.name && bytes.Equal(c.keySalt, other.keySalt)
+}
+
+func (c sdesCrypto) marshal() string {
+	return fmt.Sprintf("%d %s inline:%s", c.tag, c.suite.name, base64.StdEncoding.EncodeToString(c.keySalt))
+}
+
+// parseSDESCrypto parses an a=crypto value. Attributes with unknown
+// crypto-suites, multiple keys or an MKI are not supported and return false.
+func parseSDESCrypto(value string) (sdesCrypto, bool) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return sdesCrypto{}, false
+	}
+
+	tag, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return sdesCrypto{}, false
+	}
+
+	c := sdesCrypto{tag: tag}
+	found := false
+	for _, suite := range sdesCryptoSuites {
+		if suite.name == fields[1] {
+			c.suite, found = suite, true
+		}
+	}
+	if !found || !strings.HasPrefix(fields[2], "inline:") || strings.Contains(fields[2], ";") {
+		return sdesCrypto{}, false
+	}
+
+	// inline:<key||salt>[|lifetime][|MKI:length]
+	keyParams := strings.Split(strings.TrimPrefix(fields[2], "inline:"), "|")
+	for _, param := range keyParams[1:] {
+		if strings.Contains(param, ":") {
+			return sdesCrypto{}, false
+		}
+	}
+
+	if c.keySalt, err = base64.StdEncoding.DecodeString(keyParams[0]); err != nil {
+		if c.keySalt, err = base64.RawStdEncoding.DecodeString(keyParams[0]); err != nil {
+			return sdesCrypto{}, false
+		}
+	}
+	if len(c.keySalt) != c.suite.keyLen+c.suite.saltLen {
+		return sdesCrypto{}, false
+	}
+
+	return c, true
+}
+
+// extractSDESCryptos returns the supported a=crypto attributes of desc. All
+// media sections are bundled on one transport, so the first media section
+// carrying a=crypto attributes is used.
+func extractSDESCryptos(desc *sdp.SessionDescription) []sdesCrypto {
+	for _, m := range desc.MediaDescriptions {
+		cryptos := []sdesCrypto{}
+		haveCrypto := false
+		for _, a := range m.Attributes {
+			if a.Key != sdpAttributeCrypto {
+				continue
+			}
+
+			haveCrypto = true
+			if c, ok := parseSDESCrypto(a.Value); ok {
+				cryptos = append(cryptos, c)
+			}
+		}
+
+		if haveCrypto {
+			return cryptos
+		}
+	}
+
+	return nil
+}
+
+// newSDESLocalCryptos generates the local master key and salt of every
+// supported crypto-suite
+func newSDESLocalCryptos() ([]sdesCrypto, error) {
+	cryptos := make([]sdesCrypto, 0, len(sdesCryptoSuites))
+	for i, suite := range sdesCryptoSuites {
+		keySalt := make([]byte, suite.keyLen+suite.saltLen)
+		if _, err := rand.Read(keySalt); err != nil {
+			return nil, err
+		}
+		cryptos = append(cryptos, sdesCrypto{tag: i + 1, suite: suite, keySalt: keySalt})
+	}
+
+	return cryptos, nil
+}
+
+// selectSDESCrypto picks the remote a=crypto attribute SRTP is keyed with.
+// An answer has to accept one of the offered attributes, for an offer the
+// first supported attribute is used. It returns true if the selected
+// attribute keys SRTP differently than the previously selected one.
+func (pc *PeerConnection) selectSDESCrypto(desc *sdp.SessionDescription, weOffer bool) (bool, error) {
+	offered := pc.sdesOfferedCryptos()
+	for _, remote := range extractSDESCryptos(desc) {
+		if weOffer && !sdesCryptoOffered(offered, remote) {
+			continue
+		}
+
+		previous, selected := pc.sdesRemoteCrypto.Load().(sdesCrypto)
+		pc.sdesRemoteCrypto.Store(remote)
+		return selected && !previous.sameKey(remote), nil
+	}
+
+	return false, &rtcerr.InvalidAccessError{Err: ErrSessionDescriptionNoSDESCrypto}
+}
+
+func sdesCryptoOffered(offered []sdesCrypto, remote sdesCrypto) bool {
+	for _, c := range offered {
+		if c.tag == remote.tag && c.suite.name == remote.suite.name {
+			return true
+		}
+	}
+	return false
+}
+
+// sdesCryptos returns the local and remote a=crypto attributes SRTP is keyed
+// with, selected is false until the remote attribute has been selected
+func (pc *PeerConnection) sdesCryptos() (local, remote sdesCrypto, selected bool) {
+	if remote, selected = pc.sdesRemoteCrypto.Load().(sdesCrypto); !selected {
+		return sdesCrypto{}, sdesCrypto{}, false
+	}
+
+	for _, local = range pc.sdesLocalCryptos {
+		if local.suite.name == remote.suite.name {
+			local.tag = remote.tag
+			break
+		}
+	}
+	return local, remote, true
+}
+
+// sdesOfferedCryptos returns the local a=crypto attributes put into a
+// session description. Once SRTP is keyed only the attribute in use is
+// offered again, with the tag the remote selected it with.
+func (pc *PeerConnection) sdesOfferedCryptos() []sdesCrypto {
+	if local, _, selected := pc.sdesCryptos(); selected {
+		return []sdesCrypto{local}
+	}
+	return pc.sdesLocalCryptos
+}
+
+// populateSDESCryptos adds the local a=crypto attributes to the media
+// sections of d and removes the DTLS a=setup attributes. Offers use
+// RTP/SAVPF, an answer mirrors the proto of the offered media section as
+// required by RFC 3264, e.g. RTP/SAVP for legacy SIP endpoints.
+func (pc *PeerConnection) populateSDESCryptos(d *sdp.SessionDescription, remoteDescription *SessionDescription) {
+	cryptos := pc.sdesOfferedCryptos()
+	for _, m := range d.MediaDescriptions {
+		attributes := m.Attributes[:0]
+		for _, a := range m.Attributes {
+			if a.Key != sdp.AttrKeyConnectionSetup {
+				attributes = append(attributes, a)
+			}
+		}
+		m.Attributes = attributes
+
+		if m.MediaName.Media == mediaSectionApplication {
+			continue
+		}
+
+		m.MediaName.Protos = []string{"RTP", "SAVPF"}
+		if remoteDescription != nil {
+			if remote := getByMid(getMidValue(m), remoteDescription); remote != nil && len(remote.MediaName.Protos) != 0 {
+				m.MediaName.Protos = append([]string{}, remote.MediaName.Protos...)
+			}
+		}
+		for _, c := range cryptos {
+			m.WithValueAttribute(sdpAttributeCrypto, c.marshal())
+		}
+	}
+}
@@ -0,0 +1,229 @@
+//go:build !js
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSDESCrypto(t *testing.T) {
+	c, ok := parseSDESCrypto("1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.tag)
+	assert.Equal(t, "AES_CM_128_HMAC_SHA1_80", c.suite.name)
+	assert.Len(t, c.keySalt, 30)
+
+	for _, value := range []string{
+		"1 AES_CM_128_HMAC_SHA1_80",
+		"x AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 F8_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:WVNfX19zZW1jdGwgKCkgewkyMjA7fQp9CnVubGVz|2^20|1:4",
+		"1 AES_CM_128_HMAC_SHA1_80 inline:c2hvcnQ=",
+	} {
+		_, ok = parseSDESCrypto(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestPeerConnection_SDESSRTP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetSDESSRTP(true)
+	api := NewAPI(WithSettingEngine(s))
+	assert.NoError(t, api.mediaEngine.RegisterDefaultCodecs())
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("unsupported", nil)
+	assert.Error(t, err)
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcAnswer.OnTrack(func(trackRemote *TrackRemote, r *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "RTP/SAVPF")
+	assert.Equal(t, len(sdesCryptoSuites), strings.Count(offer.SDP, "a=crypto:"))
+	assert.NotContains(t, offer.SDP, "a=fingerprint:")
+	assert.NotContains(t, offer.SDP, "a=setup:")
+
+	offerGatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	// The answer accepts the most preferred crypto-suite
+	assert.Equal(t, 1, strings.Count(answer.SDP, "a=crypto:"))
+	assert.Contains(t, answer.SDP, "a=crypto:1 "+sdesCryptoSuites[0].name)
+
+	answerGatheringComplete := GatheringCompletePromise(pcAnswer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+
+	connected.Wait()
+	sendVideoUntilDone(onTrackFired.Done(), t, []*TrackLocalStaticSample{track})
+
+	// No DTLS handshake took place
+	assert.Nil(t, pcOffer.dtlsTransport.conn)
+	assert.Nil(t, pcAnswer.dtlsTransport.conn)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_SDESSRTP_SAVPOffer(t *testing.T) {
+	s := SettingEngine{}
+	s.SetSDESSRTP(true)
+	api := NewAPI(WithSettingEngine(s))
+	assert.NoError(t, api.mediaEngine.RegisterDefaultCodecs())
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// SIP endpoints without RTCP feedback offer RTP/SAVP
+	offer.SDP = strings.ReplaceAll(offer.SDP, "RTP/SAVPF", "RTP/SAVP")
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "m=audio 9 RTP/SAVP ")
+	assert.NotContains(t, answer.SDP, "RTP/SAVPF")
+	assert.Equal(t, 1, strings.Count(answer.SDP, "a=crypto:"))
+	assert.NotContains(t, answer.SDP, "a=fingerprint:")
+	assert.NotContains(t, answer.SDP, "a=setup:")
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_SDESSRTP_NoCrypto(t *testing.T) {
+	s := SettingEngine{}
+	s.SetSDESSRTP(true)
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+
+	// An offer keyed with DTLS is refused
+	assert.ErrorIs(t, pcAnswer.SetRemoteDescription(offer), ErrSessionDescriptionNoSDESCrypto)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestPeerConnection_SDESSRTP_Rekey(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetSDESSRTP(true)
+	api := NewAPI(WithSettingEngine(s))
+	assert.NoError(t, api.mediaEngine.RegisterDefaultCodecs())
+
+	pcOffer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeVideo, RTPTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	onTrackFired, onTrackFiredFunc := context.WithCancel(context.Background())
+	pcOffer.OnTrack(func(trackRemote *TrackRemote, r *RTPReceiver) {
+		onTrackFiredFunc()
+	})
+
+	connected := untilConnectionState(PeerConnectionStateConnected, pcOffer, pcAnswer)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offerGatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+
+	track, err := NewTrackLocalStaticSample(RTPCodecCapability{MimeType: MimeTypeVP8}, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(track)
+	assert.NoError(t, err)
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	answerGatheringComplete := GatheringCompletePromise(pcAnswer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+	connected.Wait()
+
+	previousSession, err := pcOffer.dtlsTransport.getSRTPSession()
+	assert.NoError(t, err)
+
+	// The answerer re-offers with a new key, like a SIP endpoint sending a re-INVITE
+	pcAnswer.sdesLocalCryptos, err = newSDESLocalCryptos()
+	assert.NoError(t, err)
+	reoffer, err := pcAnswer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcAnswer.SetLocalDescription(reoffer))
+	pcAnswer.rekeySDES()
+
+	assert.NoError(t, pcOffer.SetRemoteDescription(reoffer))
+	reanswer, err := pcOffer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(reanswer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(reanswer))
+
+	local, _, _ := pcAnswer.sdesCryptos()
+	_, remote, _ := pcOffer.sdesCryptos()
+	assert.True(t, local.sameKey(remote))
+
+	// Media sent with the new key is decrypted by the new SRTP session
+	sendVideoUntilDone(onTrackFired.Done(), t, []*TrackLocalStaticSample{track})
+	currentSession, err := pcOffer.dtlsTransport.getSRTPSession()
+	assert.NoError(t, err)
+	assert.NotEqual(t, previousSession, currentSession)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
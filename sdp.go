@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -613,34 +614,49 @@ func getPeerDirection(media *sdp.MediaDescription) RTPTransceiverDirection {
 	return RTPTransceiverDirection(Unknown)
 }
 
-func extractFingerprint(desc *sdp.SessionDescription) (string, string, error) {
-	fingerprints := []string{}
+// extractFingerprints returns all a=fingerprint values of desc. They may be
+// set at session or media level, but every level has to carry the same set.
+func extractFingerprints(desc *sdp.SessionDescription) ([]DTLSFingerprint, error) {
+	fingerprintSets := [][]string{}
+	appendFingerprintSet := func(attributes []sdp.Attribute) {
+		set := []string{}
+		for _, a := range attributes {
+			if a.Key == "fingerprint" {
+				set = append(set, a.Value)
+			}
+		}
 
-	if fingerprint, haveFingerprint := desc.Attribute("fingerprint"); haveFingerprint {
-		fingerprints = append(fingerprints, fingerprint)
+		if len(set) > 0 {
+			sort.Strings(set)
+			fingerprintSets = append(fingerprintSets, set)
+		}
 	}
 
+	appendFingerprintSet(desc.Attributes)
 	for _, m := range desc.MediaDescriptions {
-		if fingerprint, haveFingerprint := m.Attribute("fingerprint"); haveFingerprint {
-			fingerprints = append(fingerprints, fingerprint)
-		}
+		appendFingerprintSet(m.Attributes)
 	}
 
-	if len(fingerprints) < 1 {
-		return "", "", ErrSessionDescriptionNoFingerprint
+	if len(fingerprintSets) < 1 {
+		return nil, ErrSessionDescriptionNoFingerprint
 	}
 
-	for _, m := range fingerprints {
-		if m != fingerprints[0] {
-			return "", "", ErrSessionDescriptionConflictingFingerprints
+	for _, set := range fingerprintSets[1:] {
+		if strings.Join(set, ",") != strings.Join(fingerprintSets[0], ",") {
+			return nil, ErrSessionDescriptionConflictingFingerprints
 		}
 	}
 
-	parts := strings.Split(fingerprints[0], " ")
-	if len(parts) != 2 {
-		return "", "", ErrSessionDescriptionInvalidFingerprint
+	fingerprints := []DTLSFingerprint{}
+	for _, f := range fingerprintSets[0] {
+		parts := strings.Split(f, " ")
+		if len(parts) != 2 {
+			return nil, ErrSessionDescriptionInvalidFingerprint
+		}
+		fingerprints = append(fingerprints, DTLSFingerprint{Algorithm: parts[0], Value: parts[1]})
 	}
-	return parts[1], parts[0], nil
+
+	return fingerprints, nil
 }
 
 func extractICEDetails(desc *sdp.SessionDescription, log logging.LeveledLogger) (string, string, []ICECandidate, error) { // nolint:gocognit
@@ -14,16 +14,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestExtractFingerprint(t *testing.T) {
+func TestExtractFingerprints(t *testing.T) {
 	t.Run("Good Session Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo bar"}},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
 	})
 
 	t.Run("Good Media Fingerprint", func(t *testing.T) {
@@ -33,16 +32,15 @@ func TestExtractFingerprint(t *testing.T) {
 			},
 		}
 
-		fingerprint, hash, err := extractFingerprint(s)
+		fingerprints, err := extractFingerprints(s)
 		assert.NoError(t, err)
-		assert.Equal(t, fingerprint, "bar")
-		assert.Equal(t, hash, "foo")
+		assert.Equal(t, []DTLSFingerprint{{Algorithm: "foo", Value: "bar"}}, fingerprints)
 	})
 
 	t.Run("No Fingerprint", func(t *testing.T) {
 		s := &sdp.SessionDescription{}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionNoFingerprint, err)
 	})
 
@@ -51,7 +49,7 @@ func TestExtractFingerprint(t *testing.T) {
 			Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "foo"}},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionInvalidFingerprint, err)
 	})
 
@@ -63,7 +61,44 @@ func TestExtractFingerprint(t *testing.T) {
 			},
 		}
 
-		_, _, err := extractFingerprint(s)
+		_, err := extractFingerprints(s)
+		assert.Equal(t, ErrSessionDescriptionConflictingFingerprints, err)
+	})
+
+	t.Run("Multiple Fingerprints", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{
+					{Key: "fingerprint", Value: "sha-512 baz"},
+					{Key: "fingerprint", Value: "sha-256 bar"},
+				}},
+				{Attributes: []sdp.Attribute{
+					{Key: "fingerprint", Value: "sha-256 bar"},
+					{Key: "fingerprint", Value: "sha-512 baz"},
+				}},
+			},
+		}
+
+		fingerprints, err := extractFingerprints(s)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []DTLSFingerprint{
+			{Algorithm: "sha-256", Value: "bar"},
+			{Algorithm: "sha-512", Value: "baz"},
+		}, fingerprints)
+	})
+
+	t.Run("Conflicting Multiple Fingerprints", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{Attributes: []sdp.Attribute{
+					{Key: "fingerprint", Value: "sha-256 bar"},
+					{Key: "fingerprint", Value: "sha-512 baz"},
+				}},
+				{Attributes: []sdp.Attribute{{Key: "fingerprint", Value: "sha-256 bar"}}},
+			},
+		}
+
+		_, err := extractFingerprints(s)
 		assert.Equal(t, ErrSessionDescriptionConflictingFingerprints, err)
 	})
 }
@@ -4,6 +4,7 @@
 package webrtc
 
 import (
+	"crypto"
 	"io"
 	"time"
 
@@ -34,11 +35,13 @@ type SettingEngine struct {
 		ICESrflxAcceptanceMinWait *time.Duration
 		ICEPrflxAcceptanceMinWait *time.Duration
 		ICERelayAcceptanceMinWait *time.Duration
+		ICEAutoRestartTimeout     time.Duration
 	}
 	candidates struct {
 		ICELite                bool
 		ICENetworkTypes        []NetworkType
 		InterfaceFilter        func(string) bool
+		CandidateFilter        func(ICECandidate) (ICECandidate, bool)
 		NAT1To1IPs             []string
 		NAT1To1IPCandidateType ICECandidateType
 		MulticastDNSMode       ice.MulticastDNSMode
@@ -53,9 +56,12 @@ type SettingEngine struct {
 	}
 	dtls struct {
 		retransmissionInterval time.Duration
+		fingerprintAlgorithms  []crypto.Hash
+		verifyPeerCertificate  func(rawCerts [][]byte, fingerprints []DTLSFingerprint) error
 	}
 	sctp struct {
 		maxReceiveBufferSize uint32
+		enableZeroChecksum   bool
 	}
 	sdpMediaLevelFingerprints                 bool
 	answeringDTLSRole                         DTLSRole
@@ -71,6 +77,8 @@ type SettingEngine struct {
 	disableMediaEngineCopy                    bool
 	srtpProtectionProfiles                    []dtls.SRTPProtectionProfile
 	receiveMTU                                uint
+	certificateRotator                        *CertificateRotator
+	sdesSRTP                                  bool
 }
 
 // getReceiveMTU returns the configured MTU. If SettingEngine's MTU is configured to 0 it returns the default
@@ -89,8 +97,37 @@ func (e *SettingEngine) DetachDataChannels() {
 	e.detach.DataChannels = true
 }
 
+// SetDTLSFingerprintAlgorithms sets the hash algorithms used for the DTLS
+// fingerprints of every certificate, e.g. crypto.SHA256 and crypto.SHA512.
+// Each fingerprint is advertised in its own a=fingerprint line.
+// Defaults to SHA-256 only.
+func (e *SettingEngine) SetDTLSFingerprintAlgorithms(algorithms ...crypto.Hash) {
+	e.dtls.fingerprintAlgorithms = algorithms
+}
+
+// SetCertificateRotator sets the CertificateRotator that provides the
+// certificates of PeerConnections created without Configuration.Certificates.
+// This keeps the DTLS fingerprint stable across PeerConnections and restarts.
+func (e *SettingEngine) SetCertificateRotator(r *CertificateRotator) {
+	e.certificateRotator = r
+}
+
+// SetSDESSRTP keys SRTP with RFC 4568 a=crypto attributes exchanged in the
+// session descriptions instead of a DTLS handshake. It exists to interoperate
+// with legacy SIP endpoints. The keys are only as secret as the signaling
+// channel, and DataChannels are not available as they require DTLS.
+// ICE is still required. A remote description with a new a=crypto key, e.g.
+// from a re-INVITE, rekeys SRTP. Without a DTLS handshake the DTLSTransport
+// reports DTLSTransportStateConnected as soon as SRTP is keyed.
+func (e *SettingEngine) SetSDESSRTP(enabled bool) {
+	e.sdesSRTP = enabled
+}
+
 // SetSRTPProtectionProfiles allows the user to override the default SRTP Protection Profiles
 // The default srtp protection profiles are provided by the function `defaultSrtpProtectionProfiles`
+// Only SRTP_AEAD_AES_128_GCM, SRTP_AES128_CM_HMAC_SHA1_80 and SRTP_AES128_CM_HMAC_SHA1_32 are
+// implemented. The AES-256 profiles, like SRTP_AEAD_AES_256_GCM, are not implemented and are
+// not offered. If none of the profiles is implemented the DTLSTransport fails to start.
 func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...dtls.SRTPProtectionProfile) {
 	e.srtpProtectionProfiles = profiles
 }
@@ -99,12 +136,28 @@ func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...dtls.SRTPProtectio
 // * disconnectedTimeout is the duration without network activity before a Agent is considered disconnected. Default is 5 Seconds
 // * failedTimeout is the duration without network activity before a Agent is considered failed after disconnected. Default is 25 Seconds
 // * keepAliveInterval is how often the ICE Agent sends extra traffic if there is no activity, if media is flowing no traffic will be sent. Default is 2 seconds
+//
+// The keepalives are STUN Binding requests, so they double as consent freshness
+// checks (RFC 7675). disconnectedTimeout plus failedTimeout is the consent expiry,
+// 30 seconds by default. Once it elapses the ICEConnectionState becomes failed,
+// the candidates are released and nothing is sent anymore. Unlike RFC 7675 any
+// packet received on the selected pair refreshes consent, not only STUN responses.
 func (e *SettingEngine) SetICETimeouts(disconnectedTimeout, failedTimeout, keepAliveInterval time.Duration) {
 	e.timeout.ICEDisconnectedTimeout = &disconnectedTimeout
 	e.timeout.ICEFailedTimeout = &failedTimeout
 	e.timeout.ICEKeepaliveInterval = &keepAliveInterval
 }
 
+// SetICEAutoRestartTimeout enables restarting ICE automatically once the ICE
+// connection state has been disconnected or failed for the given duration.
+// The PeerConnection then behaves as if RestartICE was called, so the
+// application has to renegotiate from OnNegotiationNeeded. DTLS and SRTP
+// sessions are kept across the restart. A timeout of zero, the default,
+// disables automatic restarts.
+func (e *SettingEngine) SetICEAutoRestartTimeout(timeout time.Duration) {
+	e.timeout.ICEAutoRestartTimeout = timeout
+}
+
 // SetHostAcceptanceMinWait sets the ICEHostAcceptanceMinWait
 func (e *SettingEngine) SetHostAcceptanceMinWait(t time.Duration) {
 	e.timeout.ICEHostAcceptanceMinWait = &t
@@ -157,6 +210,15 @@ func (e *SettingEngine) SetInterfaceFilter(filter func(string) bool) {
 	e.candidates.InterfaceFilter = filter
 }
 
+// SetCandidateFilter sets a function that is called for every gathered local
+// candidate before it is passed to OnICECandidate or added to the SDP. The
+// candidate it returns is signaled in place of the gathered one, which can be
+// used to rewrite the address and port, e.g. per interface when running behind
+// load balancers. Returning false drops the candidate.
+func (e *SettingEngine) SetCandidateFilter(filter func(ICECandidate) (ICECandidate, bool)) {
+	e.candidates.CandidateFilter = filter
+}
+
 // SetNAT1To1IPs sets a list of external IP addresses of 1:1 (D)NAT
 // and a candidate type for which the external IP address is used.
 // This is useful when you are host a server using Pion on an AWS EC2 instance
@@ -237,6 +299,16 @@ func (e *SettingEngine) DisableCertificateFingerprintVerification(isDisabled boo
 	e.disableCertificateFingerprintVerification = isDisabled
 }
 
+// SetDTLSVerifyPeerCertificate sets a function that verifies the certificate
+// chain of the remote during the DTLS handshake. It is called with the DER
+// encoded chain and the fingerprints of the remote description, in addition
+// to the fingerprint verification. Combine it with
+// DisableCertificateFingerprintVerification to replace that. Returning an
+// error aborts the handshake with a bad_certificate alert.
+func (e *SettingEngine) SetDTLSVerifyPeerCertificate(verify func(rawCerts [][]byte, fingerprints []DTLSFingerprint) error) {
+	e.dtls.verifyPeerCertificate = verify
+}
+
 // SetDTLSReplayProtectionWindow sets a replay attack protection window size of DTLS connection.
 func (e *SettingEngine) SetDTLSReplayProtectionWindow(n uint) {
 	e.replayProtection.DTLS = &n
@@ -285,6 +357,22 @@ func (e *SettingEngine) SetICEUDPMux(udpMux ice.UDPMux) {
 	e.iceUDPMux = udpMux
 }
 
+// SetICEServerMode makes all PeerConnections share the UDP and TCP port of the
+// ICEServerMode. They run as ICE-lite, only gather host candidates on the
+// shared ports and advertise the public IPs of the ICEServerMode if set. The
+// remote address of each PeerConnection is reported by the selected candidate
+// pair of its ICETransport.
+func (e *SettingEngine) SetICEServerMode(s *ICEServerMode) {
+	e.candidates.ICELite = true
+	e.candidates.ICENetworkTypes = s.networkTypes()
+	if len(s.publicIPs) > 0 {
+		e.candidates.NAT1To1IPs = s.publicIPs
+		e.candidates.NAT1To1IPCandidateType = ICECandidateTypeHost
+	}
+	e.iceUDPMux = s.udpMux
+	e.iceTCPMux = s.tcpMux
+}
+
 // SetICEProxyDialer sets the proxy dialer interface based on golang.org/x/net/proxy.
 func (e *SettingEngine) SetICEProxyDialer(d proxy.Dialer) {
 	e.iceProxyDialer = d
@@ -313,3 +401,12 @@ func (e *SettingEngine) SetDTLSRetransmissionInterval(interval time.Duration) {
 func (e *SettingEngine) SetSCTPMaxReceiveBufferSize(maxReceiveBufferSize uint32) {
 	e.sctp.maxReceiveBufferSize = maxReceiveBufferSize
 }
+
+// EnableSCTPZeroChecksum controls the zero checksum feature in SCTP (RFC 9653).
+// When both peers support it, the CRC32c checksum of every SCTP packet is skipped
+// since DTLS already provides integrity protection. This reduces CPU usage and latency.
+// The feature is negotiated during the SCTP handshake, so it is safe to enable against
+// remotes that don't support it. SCTPTransport.GetCapabilities reports it.
+func (e *SettingEngine) EnableSCTPZeroChecksum(isEnabled bool) {
+	e.sctp.enableZeroChecksum = isEnabled
+}
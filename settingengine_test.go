@@ -5,6 +5,7 @@ package webrtc
 
 import (
 	"net"
+	"reflect"
 	"testing"
 	"time"
 
@@ -242,3 +243,80 @@ func TestSetSCTPMaxReceiverBufferSize(t *testing.T) {
 	s.SetSCTPMaxReceiveBufferSize(expSize)
 	assert.Equal(t, expSize, s.sctp.maxReceiveBufferSize)
 }
+
+func TestEnableSCTPZeroChecksum(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	assert.False(t, s.sctp.enableZeroChecksum)
+
+	s.EnableSCTPZeroChecksum(true)
+	assert.True(t, s.sctp.enableZeroChecksum)
+
+	offerer, answerer, err := NewAPI(WithSettingEngine(s)).newPair(Configuration{})
+	assert.NoError(t, err)
+
+	messageReceived := make(chan struct{})
+	answerer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			assert.Equal(t, "zero checksum", string(msg.Data))
+			close(messageReceived)
+		})
+	})
+
+	dc, err := offerer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	dc.OnOpen(func() {
+		assert.NoError(t, dc.SendText("zero checksum"))
+	})
+
+	assert.NoError(t, signalPair(offerer, answerer))
+	<-messageReceived
+
+	assert.True(t, offerer.SCTP().GetCapabilities().ZeroChecksum)
+	assert.True(t, answerer.SCTP().GetCapabilities().ZeroChecksum)
+	assert.True(t, sctpSendsZeroChecksum(offerer))
+	assert.True(t, sctpSendsZeroChecksum(answerer))
+
+	closePairNow(t, offerer, answerer)
+
+	// Only the answerer accepts packets without checksum, so only the
+	// offerer may send them
+	offerer, err = NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerer, err = NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	dataChannelOpened := make(chan struct{})
+	answerer.OnDataChannel(func(d *DataChannel) {
+		if d.Label() != "data" {
+			return
+		}
+		d.OnOpen(func() {
+			close(dataChannelOpened)
+		})
+	})
+
+	_, err = offerer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(offerer, answerer))
+	<-dataChannelOpened
+
+	assert.False(t, offerer.SCTP().GetCapabilities().ZeroChecksum)
+	assert.True(t, sctpSendsZeroChecksum(offerer))
+	assert.False(t, sctpSendsZeroChecksum(answerer))
+
+	closePairNow(t, offerer, answerer)
+}
+
+// sctpSendsZeroChecksum returns whether the SCTP association of pc
+// negotiated to send packets without checksum. pion/sctp doesn't export it.
+func sctpSendsZeroChecksum(pc *PeerConnection) bool {
+	association := pc.SCTP().association()
+	return reflect.ValueOf(association).Elem().FieldByName("sendZeroChecksum").Bool()
+}
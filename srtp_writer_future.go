@@ -4,6 +4,7 @@
 package webrtc
 
 import (
+	"errors"
 	"io"
 	"sync"
 	"sync/atomic"
@@ -22,14 +23,18 @@ type srtpWriterFuture struct {
 	rtpWriteStream atomic.Value // *srtp.WriteStreamSRTP
 	mu             sync.Mutex
 	closed         bool
+
+	// srtpSession is the session rtpWriteStream was opened on
+	srtpSession *srtp.SessionSRTP
 }
 
 func (s *srtpWriterFuture) init(returnWhenNoSRTP bool) error {
+	srtpReady := s.rtpSender.transport.srtpReadyChan()
 	if returnWhenNoSRTP {
 		select {
 		case <-s.rtpSender.stopCalled:
 			return io.ErrClosedPipe
-		case <-s.rtpSender.transport.srtpReady:
+		case <-srtpReady:
 		default:
 			return nil
 		}
@@ -37,7 +42,7 @@ func (s *srtpWriterFuture) init(returnWhenNoSRTP bool) error {
 		select {
 		case <-s.rtpSender.stopCalled:
 			return io.ErrClosedPipe
-		case <-s.rtpSender.transport.srtpReady:
+		case <-srtpReady:
 		}
 	}
 
@@ -48,17 +53,22 @@ func (s *srtpWriterFuture) init(returnWhenNoSRTP bool) error {
 		return io.ErrClosedPipe
 	}
 
-	srtcpSession, err := s.rtpSender.transport.getSRTCPSession()
-	if err != nil {
+	// A DTLS restart may have cleared the sessions since srtpReady was closed
+	srtpSession, err := s.rtpSender.transport.getSRTPSession()
+	if errors.Is(err, errDtlsTransportNotStarted) && returnWhenNoSRTP {
+		return nil
+	} else if err != nil {
 		return err
+	} else if s.writeStream() != nil && s.srtpSession == srtpSession {
+		return nil
 	}
 
-	rtcpReadStream, err := srtcpSession.OpenReadStream(uint32(s.ssrc))
+	srtcpSession, err := s.rtpSender.transport.getSRTCPSession()
 	if err != nil {
 		return err
 	}
 
-	srtpSession, err := s.rtpSender.transport.getSRTPSession()
+	rtcpReadStream, err := srtcpSession.OpenReadStream(uint32(s.ssrc))
 	if err != nil {
 		return err
 	}
@@ -68,8 +78,38 @@ func (s *srtpWriterFuture) init(returnWhenNoSRTP bool) error {
 		return err
 	}
 
+	// After a DTLS restart the previous stream belongs to the closed SRTP
+	// session, closing it makes blocked reads retry on the new one
+	previous, _ := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP)
 	s.rtcpReadStream.Store(rtcpReadStream)
 	s.rtpWriteStream.Store(rtpWriteStream)
+	s.srtpSession = srtpSession
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// reset drops the write stream when the DTLSTransport restarts, writes are
+// discarded until init opened the streams on the new SRTP sessions
+func (s *srtpWriterFuture) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rtpWriteStream.Store((*srtp.WriteStreamSRTP)(nil))
+}
+
+// rebind opens the streams on the SRTP sessions of a restarted DTLSTransport
+// if they had been opened before
+func (s *srtpWriterFuture) rebind() error {
+	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); !ok || value == nil {
+		return nil
+	}
+
+	// A stopped RTPSender doesn't need its streams anymore
+	if err := s.init(true); !errors.Is(err, io.ErrClosedPipe) {
+		return err
+	}
 	return nil
 }
 
@@ -82,7 +122,7 @@ func (s *srtpWriterFuture) Close() error {
 	}
 	s.closed = true
 
-	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok {
+	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok && value != nil {
 		return value.Close()
 	}
 
@@ -90,8 +130,13 @@ func (s *srtpWriterFuture) Close() error {
 }
 
 func (s *srtpWriterFuture) Read(b []byte) (n int, err error) {
-	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok {
-		return value.Read(b)
+	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok && value != nil {
+		n, err = value.Read(b)
+		if err != nil && s.rtcpReadStream.Load() != value {
+			// The stream was replaced by a DTLS restart
+			return s.Read(b)
+		}
+		return n, err
 	}
 
 	if err := s.init(false); err != nil || s.rtcpReadStream.Load() == nil {
@@ -102,7 +147,7 @@ func (s *srtpWriterFuture) Read(b []byte) (n int, err error) {
 }
 
 func (s *srtpWriterFuture) SetReadDeadline(t time.Time) error {
-	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok {
+	if value, ok := s.rtcpReadStream.Load().(*srtp.ReadStreamSRTCP); ok && value != nil {
 		return value.SetReadDeadline(t)
 	}
 
@@ -113,12 +158,18 @@ func (s *srtpWriterFuture) SetReadDeadline(t time.Time) error {
 	return s.SetReadDeadline(t)
 }
 
+// writeStream returns nil until the SRTP session is available
+func (s *srtpWriterFuture) writeStream() *srtp.WriteStreamSRTP {
+	value, _ := s.rtpWriteStream.Load().(*srtp.WriteStreamSRTP)
+	return value
+}
+
 func (s *srtpWriterFuture) WriteRTP(header *rtp.Header, payload []byte) (int, error) {
-	if value, ok := s.rtpWriteStream.Load().(*srtp.WriteStreamSRTP); ok {
+	if value := s.writeStream(); value != nil {
 		return value.WriteRTP(header, payload)
 	}
 
-	if err := s.init(true); err != nil || s.rtpWriteStream.Load() == nil {
+	if err := s.init(true); err != nil || s.writeStream() == nil {
 		return 0, err
 	}
 
@@ -126,11 +177,11 @@ func (s *srtpWriterFuture) WriteRTP(header *rtp.Header, payload []byte) (int, er
 }
 
 func (s *srtpWriterFuture) Write(b []byte) (int, error) {
-	if value, ok := s.rtpWriteStream.Load().(*srtp.WriteStreamSRTP); ok {
+	if value := s.writeStream(); value != nil {
 		return value.Write(b)
 	}
 
-	if err := s.init(true); err != nil || s.rtpWriteStream.Load() == nil {
+	if err := s.init(true); err != nil || s.writeStream() == nil {
 		return 0, err
 	}
 
@@ -1174,6 +1174,10 @@ type TransportStats struct {
 	// transport, as defined in the "Profile" column of the IANA DTLS-SRTP protection
 	// profile registry.
 	SRTPCipher string `json:"srtpCipher"`
+
+	// TLSVersion is the negotiated DTLS version as four upper case hexadecimal
+	// digits of its two version bytes. Present only if DTLS is negotiated.
+	TLSVersion string `json:"tlsVersion"`
 }
 
 // StatsICECandidatePairState is the state of an ICE candidate pair used in the
@@ -28,6 +28,8 @@ type TrackRemote struct {
 	receiver         *RTPReceiver
 	peeked           []byte
 	peekedAttributes interceptor.Attributes
+
+	frameReader *frameTransformReader
 }
 
 func newTrackRemote(kind RTPCodecType, ssrc SSRC, rid string, receiver *RTPReceiver) *TrackRemote {
@@ -100,6 +102,22 @@ func (t *TrackRemote) Codec() RTPCodecParameters {
 
 // Read reads data from the track.
 func (t *TrackRemote) Read(b []byte) (n int, attributes interceptor.Attributes, err error) {
+	if transform := t.receiver.getFrameTransform(); transform != nil {
+		t.mu.Lock()
+		if t.frameReader == nil {
+			t.frameReader = newFrameTransformReader(t.codec.ClockRate, t.receiver.api.settingEngine.getReceiveMTU())
+		}
+		frameReader := t.frameReader
+		t.mu.Unlock()
+
+		return frameReader.read(t, b, transform)
+	}
+
+	return t.readRaw(b)
+}
+
+// readRaw reads the packets as they were received, before any FrameTransform
+func (t *TrackRemote) readRaw(b []byte) (n int, attributes interceptor.Attributes, err error) {
 	t.mu.RLock()
 	r := t.receiver
 	peeked := t.peeked != nil
@@ -171,9 +189,9 @@ func (t *TrackRemote) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
 	return r, attributes, nil
 }
 
-// peek is like Read, but it doesn't discard the packet read
+// peek is like readRaw, but it doesn't discard the packet read
 func (t *TrackRemote) peek(b []byte) (n int, a interceptor.Attributes, err error) {
-	n, a, err = t.Read(b)
+	n, a, err = t.readRaw(b)
 	if err != nil {
 		return
 	}